@@ -0,0 +1,351 @@
+package asg
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+)
+
+// protocolOther stands for the protocols other than tcp, udp, icmp and
+// icmpv6, whose traffic only rules for all protocols allow.
+const protocolOther = "other"
+
+// Change describes the destinations that became allowed or denied for one
+// combination of protocol, ports and ICMP type/code.
+type Change struct {
+	Protocol string            `json:"protocol"`
+	Ports    string            `json:"ports,omitempty"`
+	Type     *int              `json:"type,omitempty"`
+	Code     *int              `json:"code,omitempty"`
+	Allowed  []iptools.IPRange `json:"allowed"`
+	Denied   []iptools.IPRange `json:"denied"`
+}
+
+func (c Change) Traffic() string {
+	return describeTraffic(c.Protocol, c.Ports, c.Type, c.Code)
+}
+
+func describeTraffic(protocol, ports string, icmpType, icmpCode *int) string {
+	switch {
+	case protocol == protocolOther:
+		return "other protocols"
+	case ports != "":
+		return fmt.Sprintf("%s %s", protocol, ports)
+	case icmpType != nil && icmpCode != nil:
+		return fmt.Sprintf("%s type %d code %d", protocol, *icmpType, *icmpCode)
+	default:
+		return protocol
+	}
+}
+
+// trafficCell is a piece of traffic that each rule allows either entirely or
+// not at all: a range of ports of tcp or udp, an ICMP type and code, where -1
+// stands for every value no rule names, or a whole other protocol.
+type trafficCell struct {
+	protocol string
+	ports    PortRange
+	icmpType int
+	icmpCode int
+}
+
+type parsedRule struct {
+	Rule
+	ipRange iptools.IPRange
+	ports   []PortRange
+}
+
+func (r parsedRule) allows(cell trafficCell) bool {
+	if r.Protocol == ProtocolAll {
+		return true
+	}
+
+	if r.Protocol != cell.protocol {
+		return false
+	}
+
+	switch r.Protocol {
+	case ProtocolTCP, ProtocolUDP:
+		for _, portRange := range r.ports {
+			if portRange.Start <= cell.ports.Start && cell.ports.End <= portRange.End {
+				return true
+			}
+		}
+		return false
+	case ProtocolICMP, ProtocolICMPv6:
+		return icmpCovers(r.Type, cell.icmpType) && icmpCovers(r.Code, cell.icmpCode)
+	default:
+		return true
+	}
+}
+
+func icmpCovers(ruleValue *int, value int) bool {
+	return ruleValue == nil || *ruleValue == -1 || *ruleValue == value
+}
+
+type cellChange struct {
+	cell            trafficCell
+	allowed, denied iptools.IPSet
+}
+
+func (c cellChange) empty() bool {
+	return c.allowed.Empty() && c.denied.Empty()
+}
+
+func (c cellChange) sameAs(other cellChange) bool {
+	return sameRanges(c.allowed.Ranges(), other.allowed.Ranges()) && sameRanges(c.denied.Ranges(), other.denied.Ranges())
+}
+
+// Diff compares the traffic allowed by two sets of rules and returns which
+// ranges are allowed by newRules but not by oldRules and vice versa. Rules are
+// compared by the traffic they allow, so reordered ports or a rule for all
+// protocols replaced by rules for each protocol are not reported. Ranges that
+// changed for all traffic alike are reported once for protocol all, and the
+// remaining changes for each protocol and range of ports or ICMP type/code.
+func Diff(oldRules, newRules []Rule) ([]Change, error) {
+	oldParsed, err := parseRules(oldRules)
+	if err != nil {
+		return nil, err
+	}
+
+	newParsed, err := parseRules(newRules)
+	if err != nil {
+		return nil, err
+	}
+
+	oldRuleSet, newRuleSet := newRuleSet(oldParsed), newRuleSet(newParsed)
+
+	var cellChanges []cellChange
+	for _, cell := range trafficCells(append(append([]parsedRule{}, oldParsed...), newParsed...)) {
+		oldSet := oldRuleSet.allowed(cell)
+		newSet := newRuleSet.allowed(cell)
+
+		cellChanges = append(cellChanges, cellChange{
+			cell:    cell,
+			allowed: newSet.Difference(oldSet),
+			denied:  oldSet.Difference(newSet),
+		})
+	}
+
+	common := cellChanges[0]
+	for _, change := range cellChanges[1:] {
+		common.allowed = common.allowed.Intersect(change.allowed)
+		common.denied = common.denied.Intersect(change.denied)
+	}
+
+	var result []Change
+	if !common.empty() {
+		result = append(result, Change{
+			Protocol: ProtocolAll,
+			Allowed:  append([]iptools.IPRange{}, common.allowed.Ranges()...),
+			Denied:   append([]iptools.IPRange{}, common.denied.Ranges()...),
+		})
+	}
+
+	for i := range cellChanges {
+		cellChanges[i].allowed = cellChanges[i].allowed.Difference(common.allowed)
+		cellChanges[i].denied = cellChanges[i].denied.Difference(common.denied)
+	}
+
+	for i := 0; i < len(cellChanges); i++ {
+		change := cellChanges[i]
+
+		// merge adjacent port ranges with the same change
+		ports := change.cell.ports
+		for i+1 < len(cellChanges) && cellChanges[i+1].cell.protocol == change.cell.protocol &&
+			cellChanges[i+1].cell.ports.Start == ports.End+1 && cellChanges[i+1].sameAs(change) {
+			i++
+			ports.End = cellChanges[i].cell.ports.End
+		}
+
+		if change.empty() {
+			continue
+		}
+
+		c := Change{
+			Protocol: change.cell.protocol,
+			Allowed:  append([]iptools.IPRange{}, change.allowed.Ranges()...),
+			Denied:   append([]iptools.IPRange{}, change.denied.Ranges()...),
+		}
+
+		switch c.Protocol {
+		case ProtocolTCP, ProtocolUDP:
+			c.Ports = portsString(ports)
+		case ProtocolICMP, ProtocolICMPv6:
+			icmpType, icmpCode := change.cell.icmpType, change.cell.icmpCode
+			c.Type, c.Code = &icmpType, &icmpCode
+		}
+
+		result = append(result, c)
+	}
+
+	return result, nil
+}
+
+func parseRules(rules []Rule) ([]parsedRule, error) {
+	var parsed []parsedRule
+
+	for _, rule := range rules {
+		ipRange, err := rule.DestinationRange()
+		if err != nil {
+			return nil, fmt.Errorf("invalid destination '%s': %s", rule.Destination, err)
+		}
+
+		var ports []PortRange
+		if rule.Protocol == ProtocolTCP || rule.Protocol == ProtocolUDP {
+			ports, err = ParsePorts(rule.Ports)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ports '%s': %s", rule.Ports, err)
+			}
+		}
+
+		parsed = append(parsed, parsedRule{Rule: rule, ipRange: ipRange, ports: ports})
+	}
+
+	return parsed, nil
+}
+
+// trafficCells splits all traffic into the cells that the rules allow either
+// entirely or not at all, ordered by protocol and then by port or ICMP
+// type/code.
+func trafficCells(rules []parsedRule) []trafficCell {
+	var cells []trafficCell
+
+	for _, protocol := range []string{ProtocolTCP, ProtocolUDP} {
+		bounds := map[int]bool{1: true, 65536: true}
+		for _, rule := range rules {
+			if rule.Protocol == protocol {
+				for _, portRange := range rule.ports {
+					bounds[portRange.Start] = true
+					bounds[portRange.End+1] = true
+				}
+			}
+		}
+
+		starts := sortedKeys(bounds)
+		for i := 0; i+1 < len(starts); i++ {
+			cells = append(cells, trafficCell{protocol: protocol, ports: PortRange{Start: starts[i], End: starts[i+1] - 1}})
+		}
+	}
+
+	for _, protocol := range []string{ProtocolICMP, ProtocolICMPv6} {
+		types := map[int]bool{}
+		codes := map[int]bool{}
+		for _, rule := range rules {
+			if rule.Protocol != protocol {
+				continue
+			}
+			if rule.Type != nil && *rule.Type != -1 {
+				types[*rule.Type] = true
+			}
+			if rule.Code != nil && *rule.Code != -1 {
+				codes[*rule.Code] = true
+			}
+		}
+
+		for _, icmpType := range append(sortedKeys(types), -1) {
+			for _, icmpCode := range append(sortedKeys(codes), -1) {
+				cells = append(cells, trafficCell{protocol: protocol, icmpType: icmpType, icmpCode: icmpCode})
+			}
+		}
+	}
+
+	others := map[string]bool{}
+	for _, rule := range rules {
+		switch rule.Protocol {
+		case ProtocolAll, ProtocolTCP, ProtocolUDP, ProtocolICMP, ProtocolICMPv6:
+		default:
+			others[rule.Protocol] = true
+		}
+	}
+
+	var otherProtocols []string
+	for protocol := range others {
+		otherProtocols = append(otherProtocols, protocol)
+	}
+	sort.Strings(otherProtocols)
+
+	for _, protocol := range append(otherProtocols, protocolOther) {
+		cells = append(cells, trafficCell{protocol: protocol})
+	}
+
+	return cells
+}
+
+// ruleSet keeps the destinations of the rules for all protocols merged, as
+// they apply to every cell.
+type ruleSet struct {
+	all      iptools.IPSet
+	specific []parsedRule
+}
+
+func newRuleSet(rules []parsedRule) ruleSet {
+	var (
+		all []iptools.IPRange
+		set ruleSet
+	)
+
+	for _, rule := range rules {
+		if rule.Protocol == ProtocolAll {
+			all = append(all, rule.ipRange)
+		} else {
+			set.specific = append(set.specific, rule)
+		}
+	}
+
+	set.all = iptools.NewIPSet(all...)
+	return set
+}
+
+func (s ruleSet) allowed(cell trafficCell) iptools.IPSet {
+	var ipRanges []iptools.IPRange
+	for _, rule := range s.specific {
+		if rule.allows(cell) {
+			ipRanges = append(ipRanges, rule.ipRange)
+		}
+	}
+
+	return s.all.Union(iptools.NewIPSet(ipRanges...))
+}
+
+func sortedKeys(m map[int]bool) []int {
+	var keys []int
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func portsString(ports PortRange) string {
+	if ports.Start == ports.End {
+		return fmt.Sprintf("%d", ports.Start)
+	}
+
+	return fmt.Sprintf("%d-%d", ports.Start, ports.End)
+}
+
+func sameRanges(a, b []iptools.IPRange) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !a[i].EqualsRange(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r Rule) trafficKey() string {
+	key := fmt.Sprintf("%s|%s", r.Protocol, r.Ports)
+	if r.Type != nil {
+		key += fmt.Sprintf("|type=%d", *r.Type)
+	}
+	if r.Code != nil {
+		key += fmt.Sprintf("|code=%d", *r.Code)
+	}
+	return key
+}
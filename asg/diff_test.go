@@ -0,0 +1,231 @@
+package asg_test
+
+import (
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diff", func() {
+	var (
+		oldRules []asg.Rule
+		newRules []asg.Rule
+		changes  []asg.Change
+		diffErr  error
+	)
+
+	intPtr := func(i int) *int { return &i }
+
+	rangeStrings := func(ipRanges []iptools.IPRange) []string {
+		strs := []string{}
+		for i := range ipRanges {
+			strs = append(strs, ipRanges[i].String())
+		}
+		return strs
+	}
+
+	JustBeforeEach(func() {
+		changes, diffErr = asg.Diff(oldRules, newRules)
+	})
+
+	Context("when the rules cover the same address space split differently", func() {
+		BeforeEach(func() {
+			oldRules = []asg.Rule{
+				{Protocol: "all", Destination: "10.0.0.0-10.0.0.255"},
+			}
+			newRules = []asg.Rule{
+				{Protocol: "all", Destination: "10.0.0.0/25"},
+				{Protocol: "all", Destination: "10.0.0.128-10.0.0.255"},
+			}
+		})
+
+		It("returns no changes", func() {
+			Expect(diffErr).NotTo(HaveOccurred())
+			Expect(changes).To(BeEmpty())
+		})
+	})
+
+	Context("when an exclude was added", func() {
+		BeforeEach(func() {
+			oldRules = []asg.Rule{
+				{Protocol: "all", Destination: "10.0.0.0-10.0.0.255"},
+			}
+			newRules = []asg.Rule{
+				{Protocol: "all", Destination: "10.0.0.0-10.0.0.4"},
+				{Protocol: "all", Destination: "10.0.0.6-10.0.0.255"},
+			}
+		})
+
+		It("reports the newly denied range", func() {
+			Expect(diffErr).NotTo(HaveOccurred())
+			Expect(changes).To(HaveLen(1))
+			Expect(changes[0].Protocol).To(Equal("all"))
+			Expect(rangeStrings(changes[0].Allowed)).To(BeEmpty())
+			Expect(rangeStrings(changes[0].Denied)).To(Equal([]string{"10.0.0.5"}))
+		})
+	})
+
+	Context("when the protocol or ports of a destination changed", func() {
+		BeforeEach(func() {
+			oldRules = []asg.Rule{
+				{Protocol: "tcp", Destination: "10.0.0.0/24", Ports: "80"},
+			}
+			newRules = []asg.Rule{
+				{Protocol: "tcp", Destination: "10.0.0.0/24", Ports: "443"},
+			}
+		})
+
+		It("reports each combination separately", func() {
+			Expect(diffErr).NotTo(HaveOccurred())
+			Expect(changes).To(HaveLen(2))
+
+			Expect(changes[0].Traffic()).To(Equal("tcp 80"))
+			Expect(rangeStrings(changes[0].Denied)).To(Equal([]string{"10.0.0.0-10.0.0.255"}))
+
+			Expect(changes[1].Traffic()).To(Equal("tcp 443"))
+			Expect(rangeStrings(changes[1].Allowed)).To(Equal([]string{"10.0.0.0-10.0.0.255"}))
+		})
+	})
+
+	Context("when the ports of a rule are reordered or split", func() {
+		BeforeEach(func() {
+			oldRules = []asg.Rule{
+				{Protocol: "tcp", Destination: "10.0.0.0/24", Ports: "80,443"},
+				{Protocol: "udp", Destination: "10.0.0.0/24", Ports: "1000-2000"},
+			}
+			newRules = []asg.Rule{
+				{Protocol: "tcp", Destination: "10.0.0.0/24", Ports: "443,80"},
+				{Protocol: "udp", Destination: "10.0.0.0/24", Ports: "1000-1499"},
+				{Protocol: "udp", Destination: "10.0.0.0/24", Ports: "1500-2000"},
+			}
+		})
+
+		It("returns no changes", func() {
+			Expect(diffErr).NotTo(HaveOccurred())
+			Expect(changes).To(BeEmpty())
+		})
+	})
+
+	Context("when a rule for all protocols is narrowed to some ports", func() {
+		BeforeEach(func() {
+			oldRules = []asg.Rule{
+				{Protocol: "all", Destination: "10.0.0.0/24"},
+			}
+			newRules = []asg.Rule{
+				{Protocol: "tcp", Destination: "10.0.0.0/24", Ports: "80"},
+				{Protocol: "tcp", Destination: "10.0.0.0/24", Ports: "443"},
+			}
+		})
+
+		It("reports the rest of the traffic as denied and nothing as allowed", func() {
+			Expect(diffErr).NotTo(HaveOccurred())
+
+			var traffic []string
+			for _, change := range changes {
+				traffic = append(traffic, change.Traffic())
+				Expect(change.Allowed).To(BeEmpty())
+				Expect(rangeStrings(change.Denied)).To(Equal([]string{"10.0.0.0-10.0.0.255"}))
+			}
+
+			Expect(traffic).To(Equal([]string{
+				"tcp 1-79",
+				"tcp 81-442",
+				"tcp 444-65535",
+				"udp 1-65535",
+				"icmp type -1 code -1",
+				"icmpv6 type -1 code -1",
+				"other protocols",
+			}))
+		})
+	})
+
+	Context("when rules for each protocol are replaced by a rule for all protocols", func() {
+		BeforeEach(func() {
+			oldRules = []asg.Rule{
+				{Protocol: "tcp", Destination: "10.0.0.0/24", Ports: "1-65535"},
+				{Protocol: "udp", Destination: "10.0.0.0/24", Ports: "1-65535"},
+			}
+			newRules = []asg.Rule{
+				{Protocol: "all", Destination: "10.0.0.0/24"},
+			}
+		})
+
+		It("only reports the traffic that was not allowed before", func() {
+			Expect(diffErr).NotTo(HaveOccurred())
+
+			var traffic []string
+			for _, change := range changes {
+				traffic = append(traffic, change.Traffic())
+				Expect(rangeStrings(change.Allowed)).To(Equal([]string{"10.0.0.0-10.0.0.255"}))
+			}
+
+			Expect(traffic).To(Equal([]string{
+				"icmp type -1 code -1",
+				"icmpv6 type -1 code -1",
+				"other protocols",
+			}))
+		})
+	})
+
+	Context("when an ICMP rule is widened to every type and code", func() {
+		BeforeEach(func() {
+			oldRules = []asg.Rule{
+				{Protocol: "icmp", Destination: "10.0.0.1", Type: intPtr(8), Code: intPtr(0)},
+			}
+			newRules = []asg.Rule{
+				{Protocol: "icmp", Destination: "10.0.0.1", Type: intPtr(-1), Code: intPtr(-1)},
+			}
+		})
+
+		It("reports the other types and codes as allowed", func() {
+			Expect(diffErr).NotTo(HaveOccurred())
+
+			var traffic []string
+			for _, change := range changes {
+				traffic = append(traffic, change.Traffic())
+				Expect(rangeStrings(change.Allowed)).To(Equal([]string{"10.0.0.1"}))
+			}
+
+			Expect(traffic).To(Equal([]string{
+				"icmp type 8 code -1",
+				"icmp type -1 code 0",
+				"icmp type -1 code -1",
+			}))
+		})
+	})
+
+	Context("when a destination is invalid", func() {
+		BeforeEach(func() {
+			oldRules = []asg.Rule{{Protocol: "all", Destination: "nope"}}
+			newRules = nil
+		})
+
+		It("returns an error", func() {
+			Expect(diffErr).To(HaveOccurred())
+		})
+	})
+
+	Context("when a destination range ends before it starts", func() {
+		BeforeEach(func() {
+			oldRules = []asg.Rule{{Protocol: "all", Destination: "10.0.0.1"}}
+			newRules = []asg.Rule{{Protocol: "all", Destination: "10.0.0.9-10.0.0.1"}}
+		})
+
+		It("returns an error", func() {
+			Expect(diffErr).To(MatchError("invalid destination '10.0.0.9-10.0.0.1': invalid-range-start-after-end: 10.0.0.9-10.0.0.1"))
+		})
+	})
+
+	Context("when a destination range mixes address families", func() {
+		BeforeEach(func() {
+			oldRules = []asg.Rule{{Protocol: "all", Destination: "10.0.0.1-2001:db8::1"}}
+			newRules = nil
+		})
+
+		It("returns an error", func() {
+			Expect(diffErr).To(MatchError(ContainSubstring("invalid-range-mixed-address-families")))
+		})
+	})
+})
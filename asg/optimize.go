@@ -0,0 +1,84 @@
+package asg
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+)
+
+// OptimizedGroup describes the rules sharing one combination of protocol,
+// ports, ICMP type/code and log setting, before and after their destinations
+// were merged.
+type OptimizedGroup struct {
+	Protocol    string
+	Ports       string
+	Type        *int
+	Code        *int
+	Log         bool
+	RulesBefore int
+	RulesAfter  int
+	Addresses   iptools.IPSet
+}
+
+func (g OptimizedGroup) Traffic() string {
+	traffic := describeTraffic(g.Protocol, g.Ports, g.Type, g.Code)
+	if g.Log {
+		traffic += " (log)"
+	}
+	return traffic
+}
+
+// Optimize returns the smallest set of rules allowing the same traffic as
+// rules: rules with identical protocol, ports, ICMP type/code and log setting
+// are combined and their destinations merged into disjoint ranges. Groups
+// keep the order in which they first appear in rules.
+func Optimize(rules []Rule) ([]Rule, []OptimizedGroup, error) {
+	var keys []string
+	groups := map[string]*OptimizedGroup{}
+	ranges := map[string][]iptools.IPRange{}
+
+	for _, rule := range rules {
+		ipRange, err := rule.DestinationRange()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid destination '%s': %s", rule.Destination, err)
+		}
+
+		key := fmt.Sprintf("%s|log=%t", rule.trafficKey(), rule.Log)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+			groups[key] = &OptimizedGroup{
+				Protocol: rule.Protocol,
+				Ports:    rule.Ports,
+				Type:     rule.Type,
+				Code:     rule.Code,
+				Log:      rule.Log,
+			}
+		}
+
+		groups[key].RulesBefore++
+		ranges[key] = append(ranges[key], ipRange)
+	}
+
+	var optimized []Rule
+	var result []OptimizedGroup
+	for _, key := range keys {
+		group := groups[key]
+		group.Addresses = iptools.NewIPSet(ranges[key]...)
+
+		for _, ipRange := range group.Addresses.Ranges() {
+			optimized = append(optimized, Rule{
+				Protocol:    group.Protocol,
+				Destination: ipRange.String(),
+				Ports:       group.Ports,
+				Type:        group.Type,
+				Code:        group.Code,
+				Log:         group.Log,
+			})
+		}
+
+		group.RulesAfter = len(group.Addresses.Ranges())
+		result = append(result, *group)
+	}
+
+	return optimized, result, nil
+}
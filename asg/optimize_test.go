@@ -0,0 +1,83 @@
+package asg_test
+
+import (
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Optimize", func() {
+	intPtr := func(i int) *int { return &i }
+
+	It("merges overlapping and adjacent destinations of identical rules", func() {
+		rules := []asg.Rule{
+			{Protocol: "tcp", Destination: "10.0.0.0/25", Ports: "443"},
+			{Protocol: "all", Destination: "11.0.0.0-11.0.0.9"},
+			{Protocol: "tcp", Destination: "10.0.0.128-10.0.0.255", Ports: "443"},
+			{Protocol: "tcp", Destination: "10.0.0.10", Ports: "443"},
+			{Protocol: "tcp", Destination: "10.0.2.0/24", Ports: "443"},
+			{Protocol: "all", Destination: "11.0.0.5-11.0.0.20"},
+		}
+
+		optimized, groups, err := asg.Optimize(rules)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(optimized).To(Equal([]asg.Rule{
+			{Protocol: "tcp", Destination: "10.0.0.0-10.0.0.255", Ports: "443"},
+			{Protocol: "tcp", Destination: "10.0.2.0-10.0.2.255", Ports: "443"},
+			{Protocol: "all", Destination: "11.0.0.0-11.0.0.20"},
+		}))
+
+		Expect(groups).To(HaveLen(2))
+		Expect(groups[0].Traffic()).To(Equal("tcp 443"))
+		Expect(groups[0].RulesBefore).To(Equal(4))
+		Expect(groups[0].RulesAfter).To(Equal(2))
+		Expect(groups[0].Addresses.Size().Int64()).To(Equal(int64(512)))
+		Expect(groups[1].Traffic()).To(Equal("all"))
+		Expect(groups[1].RulesBefore).To(Equal(2))
+		Expect(groups[1].RulesAfter).To(Equal(1))
+	})
+
+	It("keeps rules with different ports, ICMP type/code or log setting apart", func() {
+		rules := []asg.Rule{
+			{Protocol: "tcp", Destination: "10.0.0.0/25", Ports: "443"},
+			{Protocol: "tcp", Destination: "10.0.0.128/25", Ports: "80"},
+			{Protocol: "tcp", Destination: "10.0.0.128/25", Ports: "443", Log: true},
+			{Protocol: "icmp", Destination: "10.0.0.0/25", Type: intPtr(0), Code: intPtr(0)},
+			{Protocol: "icmp", Destination: "10.0.0.128/25", Type: intPtr(8), Code: intPtr(0)},
+		}
+
+		optimized, groups, err := asg.Optimize(rules)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(optimized).To(HaveLen(5))
+		Expect(groups).To(HaveLen(5))
+		Expect(groups[2].Traffic()).To(Equal("tcp 443 (log)"))
+		Expect(groups[4].Traffic()).To(Equal("icmp type 8 code 0"))
+	})
+
+	It("keeps IPv4 and IPv6 destinations in separate rules", func() {
+		optimized, _, err := asg.Optimize([]asg.Rule{
+			{Protocol: "all", Destination: "::/0"},
+			{Protocol: "all", Destination: "0.0.0.0/0"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(optimized).To(Equal([]asg.Rule{
+			{Protocol: "all", Destination: "0.0.0.0-255.255.255.255"},
+			{Protocol: "all", Destination: "::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+		}))
+	})
+
+	It("returns an error for an invalid destination", func() {
+		_, _, err := asg.Optimize([]asg.Rule{
+			{Protocol: "all", Destination: "10.0.0.300"},
+		})
+		Expect(err).To(MatchError(ContainSubstring("invalid destination '10.0.0.300'")))
+	})
+
+	It("returns an error for a destination range that ends before it starts", func() {
+		_, _, err := asg.Optimize([]asg.Rule{
+			{Protocol: "all", Destination: "10.0.0.9-10.0.0.1"},
+		})
+		Expect(err).To(MatchError("invalid destination '10.0.0.9-10.0.0.1': invalid-range-start-after-end: 10.0.0.9-10.0.0.1"))
+	})
+})
@@ -2,17 +2,53 @@ package asg
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net"
 	"strings"
+
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
 )
 
 type Rule struct {
-	Protocol    string `json:"protocol"`
-	Destination string `json:"destination"`
-	Ports       string `json:"ports,omitempty"`
-	Type        string `json:"type,omitempty"`
-	Code        string `json:"code,omitempty"`
-	Log         bool   `json:"log,omitempty"`
+	Protocol    string `json:"protocol" yaml:"protocol"`
+	Destination string `json:"destination" yaml:"destination"`
+	Ports       string `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Type        *int   `json:"type,omitempty" yaml:"type,omitempty"`
+	Code        *int   `json:"code,omitempty" yaml:"code,omitempty"`
+	Log         bool   `json:"log,omitempty" yaml:"log,omitempty"`
+}
+
+func LoadRules(path string) ([]Rule, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	err = json.Unmarshal(bs, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rules in %s: %s", path, err)
+	}
+
+	return rules, nil
+}
+
+// DestinationRange parses the destination of the rule, rejecting ranges that
+// end before they start or mix address families.
+func (r Rule) DestinationRange() (iptools.IPRange, error) {
+	ipRange, err := iptools.ParseIPRange(r.Destination)
+	if err != nil {
+		return iptools.IPRange{}, err
+	}
+
+	err = ipRange.Validate()
+	if err != nil {
+		return iptools.IPRange{}, err
+	}
+
+	return ipRange, nil
 }
 
 func (r Rule) Contains(ipString string) bool {
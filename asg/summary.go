@@ -0,0 +1,173 @@
+package asg
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+)
+
+const (
+	ClassRFC1918     = "rfc1918"
+	ClassUniqueLocal = "unique-local"
+	ClassPublic      = "public"
+	ClassPublicIPv6  = "public-ipv6"
+)
+
+// Summary describes the address space allowed by a set of rules.
+type Summary struct {
+	Rules         int               `json:"rules"`
+	Protocols     []ProtocolSummary `json:"protocols"`
+	Classes       []ClassSummary    `json:"classes"`
+	LargestBlocks []iptools.IPRange `json:"largest_blocks"`
+	PrivateGaps   []iptools.IPRange `json:"private_gaps"`
+}
+
+// ProtocolSummary counts the rules and distinct destination addresses of one
+// protocol, regardless of ports or ICMP type/code.
+type ProtocolSummary struct {
+	Protocol  string   `json:"protocol"`
+	Rules     int      `json:"rules"`
+	Addresses *big.Int `json:"addresses"`
+}
+
+// ClassSummary describes how much of an address class is allowed by any rule.
+type ClassSummary struct {
+	Name     string   `json:"name"`
+	Size     *big.Int `json:"size"`
+	Allowed  *big.Int `json:"allowed"`
+	Fraction float64  `json:"fraction"`
+}
+
+type addressClass struct {
+	name string
+	set  iptools.IPSet
+}
+
+// Summarize reports the addresses allowed per protocol, how much of the
+// RFC1918, unique local, public and special-purpose address classes is
+// allowed, every allowed block from largest to smallest, and the private
+// ranges denied between allowed ranges of the same private block.
+func Summarize(rules []Rule) (Summary, error) {
+	summary := Summary{
+		Rules:         len(rules),
+		Protocols:     []ProtocolSummary{},
+		Classes:       []ClassSummary{},
+		LargestBlocks: []iptools.IPRange{},
+		PrivateGaps:   []iptools.IPRange{},
+	}
+
+	var protocols []string
+	rangesByProtocol := map[string][]iptools.IPRange{}
+	rulesByProtocol := map[string]int{}
+
+	var allowedRanges []iptools.IPRange
+	for _, rule := range rules {
+		ipRange, err := rule.DestinationRange()
+		if err != nil {
+			return Summary{}, fmt.Errorf("invalid destination '%s': %s", rule.Destination, err)
+		}
+
+		if _, ok := rulesByProtocol[rule.Protocol]; !ok {
+			protocols = append(protocols, rule.Protocol)
+		}
+
+		rulesByProtocol[rule.Protocol]++
+		rangesByProtocol[rule.Protocol] = append(rangesByProtocol[rule.Protocol], ipRange)
+		allowedRanges = append(allowedRanges, ipRange)
+	}
+
+	for _, protocol := range protocols {
+		summary.Protocols = append(summary.Protocols, ProtocolSummary{
+			Protocol:  protocol,
+			Rules:     rulesByProtocol[protocol],
+			Addresses: iptools.NewIPSet(rangesByProtocol[protocol]...).Size(),
+		})
+	}
+
+	allowed := iptools.NewIPSet(allowedRanges...)
+
+	classes, err := addressClasses()
+	if err != nil {
+		return Summary{}, err
+	}
+
+	for _, class := range classes {
+		size := class.set.Size()
+		allowedSize := class.set.Intersect(allowed).Size()
+		fraction, _ := new(big.Float).Quo(new(big.Float).SetInt(allowedSize), new(big.Float).SetInt(size)).Float64()
+
+		summary.Classes = append(summary.Classes, ClassSummary{
+			Name:     class.name,
+			Size:     size,
+			Allowed:  allowedSize,
+			Fraction: fraction,
+		})
+	}
+
+	summary.LargestBlocks = append(summary.LargestBlocks, allowed.Ranges()...)
+	sort.SliceStable(summary.LargestBlocks, func(i, j int) bool {
+		return summary.LargestBlocks[i].Size().Cmp(summary.LargestBlocks[j].Size()) > 0
+	})
+
+	privateRanges := append(iptools.PrivateIPRanges(), iptools.PrivateIPv6Ranges()...)
+	for _, privateRange := range privateRanges {
+		allowedInBlock := iptools.NewIPSet(privateRange).Intersect(allowed).Ranges()
+		if len(allowedInBlock) < 2 {
+			continue
+		}
+
+		span := iptools.NewIPSet(iptools.IPRange{
+			Start: allowedInBlock[0].Start,
+			End:   allowedInBlock[len(allowedInBlock)-1].End,
+		})
+		summary.PrivateGaps = append(summary.PrivateGaps, span.Difference(allowed).Ranges()...)
+	}
+
+	return summary, nil
+}
+
+// addressClasses splits the IPv4 and IPv6 address space into the private
+// ranges, the public ranges and each special-purpose class, which are left out
+// of the public ranges. The IPv6 ranges of a special-purpose class are
+// reported as a class of their own, suffixed with -ipv6, so that the tiny
+// IPv4 share of a class does not disappear in its IPv6 share.
+func addressClasses() ([]addressClass, error) {
+	var special []addressClass
+	var specialRanges []iptools.IPRange
+	for _, name := range iptools.SpecialPurposeClasses() {
+		ranges, err := iptools.SpecialPurposeRanges(name)
+		if err != nil {
+			return nil, err
+		}
+
+		var ipv4Ranges, ipv6Ranges []iptools.IPRange
+		for _, ipRange := range ranges {
+			if ipRange.Start.To4() != nil {
+				ipv4Ranges = append(ipv4Ranges, ipRange)
+			} else {
+				ipv6Ranges = append(ipv6Ranges, ipRange)
+			}
+		}
+
+		if len(ipv4Ranges) > 0 {
+			special = append(special, addressClass{name: name, set: iptools.NewIPSet(ipv4Ranges...)})
+		}
+		if len(ipv6Ranges) > 0 {
+			special = append(special, addressClass{name: name + "-ipv6", set: iptools.NewIPSet(ipv6Ranges...)})
+		}
+		specialRanges = append(specialRanges, ranges...)
+	}
+
+	notPublic := iptools.NewIPSet(specialRanges...)
+
+	classes := []addressClass{
+		{ClassRFC1918, iptools.NewIPSet(iptools.PrivateIPRanges()...)},
+		{ClassPublic, iptools.NewIPSet(iptools.PublicIPRanges()...).Difference(notPublic)},
+		{ClassUniqueLocal, iptools.NewIPSet(iptools.PrivateIPv6Ranges()...)},
+		{ClassPublicIPv6, iptools.NewIPSet(iptools.PublicIPv6Ranges()...).Difference(notPublic)},
+	}
+
+	return append(classes, special...), nil
+}
@@ -0,0 +1,157 @@
+package asg_test
+
+import (
+	"math/big"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Summarize", func() {
+	var (
+		rules      []asg.Rule
+		summary    asg.Summary
+		summaryErr error
+	)
+
+	rangeStrings := func(ipRanges []iptools.IPRange) []string {
+		strs := []string{}
+		for i := range ipRanges {
+			strs = append(strs, ipRanges[i].String())
+		}
+		return strs
+	}
+
+	JustBeforeEach(func() {
+		summary, summaryErr = asg.Summarize(rules)
+	})
+
+	BeforeEach(func() {
+		rules = []asg.Rule{
+			{Protocol: "all", Destination: "10.0.0.0-10.0.0.4"},
+			{Protocol: "all", Destination: "10.0.0.6-10.255.255.255"},
+			{Protocol: "tcp", Destination: "8.8.8.0/24", Ports: "53"},
+			{Protocol: "tcp", Destination: "8.8.8.8", Ports: "443"},
+			{Protocol: "udp", Destination: "127.0.0.1", Ports: "53"},
+		}
+	})
+
+	It("counts the rules and distinct addresses per protocol", func() {
+		Expect(summaryErr).NotTo(HaveOccurred())
+		Expect(summary.Rules).To(Equal(5))
+		Expect(summary.Protocols).To(Equal([]asg.ProtocolSummary{
+			{Protocol: "all", Rules: 2, Addresses: big.NewInt(1<<24 - 1)},
+			{Protocol: "tcp", Rules: 2, Addresses: big.NewInt(256)},
+			{Protocol: "udp", Rules: 1, Addresses: big.NewInt(1)},
+		}))
+	})
+
+	It("reports how much of each address class is allowed", func() {
+		Expect(summaryErr).NotTo(HaveOccurred())
+
+		classes := map[string]asg.ClassSummary{}
+		for _, class := range summary.Classes {
+			classes[class.Name] = class
+		}
+
+		Expect(classes["rfc1918"].Size).To(Equal(big.NewInt(1<<24 + 1<<20 + 1<<16)))
+		Expect(classes["rfc1918"].Allowed).To(Equal(big.NewInt(1<<24 - 1)))
+		Expect(classes["rfc1918"].Fraction).To(BeNumerically("~", 0.94, 0.01))
+
+		Expect(classes["public"].Allowed).To(Equal(big.NewInt(256)))
+		Expect(classes["loopback"].Allowed).To(Equal(big.NewInt(1)))
+		Expect(classes["multicast"].Allowed).To(Equal(big.NewInt(0)))
+		Expect(classes["multicast"].Fraction).To(BeZero())
+		Expect(classes["public-ipv6"].Allowed).To(Equal(big.NewInt(0)))
+	})
+
+	It("leaves the special-purpose ranges out of the public class", func() {
+		Expect(summaryErr).NotTo(HaveOccurred())
+
+		for _, class := range summary.Classes {
+			if class.Name == "public" {
+				Expect(class.Size).To(Equal(big.NewInt(1<<32 - 17891328 - 574817536)))
+			}
+		}
+	})
+
+	It("lists the allowed blocks from largest to smallest", func() {
+		Expect(summaryErr).NotTo(HaveOccurred())
+		Expect(rangeStrings(summary.LargestBlocks)).To(Equal([]string{
+			"10.0.0.6-10.255.255.255",
+			"8.8.8.0-8.8.8.255",
+			"10.0.0.0-10.0.0.4",
+			"127.0.0.1",
+		}))
+	})
+
+	It("reports the gaps between allowed ranges of a private block", func() {
+		Expect(summaryErr).NotTo(HaveOccurred())
+		Expect(rangeStrings(summary.PrivateGaps)).To(Equal([]string{"10.0.0.5"}))
+	})
+
+	Context("when a private block is only partly allowed", func() {
+		BeforeEach(func() {
+			rules = []asg.Rule{
+				{Protocol: "all", Destination: "192.168.1.0/24"},
+				{Protocol: "tcp", Destination: "192.168.3.0/24", Ports: "443"},
+				{Protocol: "all", Destination: "172.16.0.0/16"},
+			}
+		})
+
+		It("does not report the space outside the allowed ranges as gaps", func() {
+			Expect(summaryErr).NotTo(HaveOccurred())
+			Expect(rangeStrings(summary.PrivateGaps)).To(Equal([]string{"192.168.2.0-192.168.2.255"}))
+		})
+	})
+
+	Context("when a special-purpose class is allowed for IPv4 only", func() {
+		BeforeEach(func() {
+			rules = []asg.Rule{
+				{Protocol: "all", Destination: "169.254.0.0/16"},
+				{Protocol: "all", Destination: "224.0.0.0/4"},
+			}
+		})
+
+		It("reports the IPv4 and IPv6 ranges of the class separately", func() {
+			Expect(summaryErr).NotTo(HaveOccurred())
+
+			classes := map[string]asg.ClassSummary{}
+			for _, class := range summary.Classes {
+				classes[class.Name] = class
+			}
+
+			Expect(classes["link-local"].Size).To(Equal(big.NewInt(1 << 16)))
+			Expect(classes["link-local"].Fraction).To(Equal(1.0))
+			Expect(classes["link-local-ipv6"].Allowed).To(Equal(big.NewInt(0)))
+			Expect(classes["multicast"].Fraction).To(Equal(1.0))
+			Expect(classes["multicast-ipv6"].Fraction).To(BeZero())
+			Expect(classes).To(HaveKey("discard-only-ipv6"))
+			Expect(classes).NotTo(HaveKey("discard-only"))
+			Expect(classes).NotTo(HaveKey("reserved-ipv6"))
+		})
+	})
+
+	Context("when a destination is invalid", func() {
+		BeforeEach(func() {
+			rules = []asg.Rule{{Protocol: "all", Destination: "10.0.0.300"}}
+		})
+
+		It("returns an error", func() {
+			Expect(summaryErr).To(MatchError(ContainSubstring("invalid destination '10.0.0.300'")))
+		})
+	})
+
+	Context("when a destination range ends before it starts or mixes address families", func() {
+		It("returns an error", func() {
+			_, err := asg.Summarize([]asg.Rule{{Protocol: "all", Destination: "10.0.0.9-10.0.0.1"}})
+			Expect(err).To(MatchError(ContainSubstring("invalid-range-start-after-end")))
+
+			_, err = asg.Summarize([]asg.Rule{{Protocol: "all", Destination: "10.0.0.1-2001:db8::1"}})
+			Expect(err).To(MatchError(ContainSubstring("invalid-range-mixed-address-families")))
+		})
+	})
+})
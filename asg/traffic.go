@@ -0,0 +1,63 @@
+package asg
+
+import (
+	"net"
+)
+
+// Traffic describes an outbound connection attempt. An empty Protocol matches
+// any protocol, a zero Port matches any port, and a nil Type or Code matches
+// any ICMP type or code.
+type Traffic struct {
+	Destination net.IP
+	Protocol    string
+	Port        int
+	Type        *int
+	Code        *int
+}
+
+func (r Rule) Allows(t Traffic) bool {
+	ipRange, err := r.DestinationRange()
+	if err != nil || !ipRange.Contains(t.Destination) {
+		return false
+	}
+
+	if r.Protocol == ProtocolAll || t.Protocol == "" {
+		return true
+	}
+
+	if r.Protocol != t.Protocol {
+		return false
+	}
+
+	switch r.Protocol {
+	case ProtocolTCP, ProtocolUDP:
+		if t.Port == 0 {
+			return true
+		}
+
+		portRanges, err := ParsePorts(r.Ports)
+		if err != nil {
+			return false
+		}
+
+		for _, portRange := range portRanges {
+			if portRange.Contains(t.Port) {
+				return true
+			}
+		}
+
+		return false
+	case ProtocolICMP, ProtocolICMPv6:
+		return icmpAllows(r.Type, t.Type) && icmpAllows(r.Code, t.Code)
+	}
+
+	return false
+}
+
+func icmpAllows(ruleValue, value *int) bool {
+	if value == nil {
+		return true
+	}
+
+	return ruleValue != nil && (*ruleValue == -1 || *ruleValue == *value)
+}
@@ -0,0 +1,91 @@
+package asg_test
+
+import (
+	"net"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Allows", func() {
+	var rule asg.Rule
+
+	intPtr := func(i int) *int {
+		return &i
+	}
+
+	traffic := func(ip, protocol string, port int) asg.Traffic {
+		return asg.Traffic{
+			Destination: net.ParseIP(ip),
+			Protocol:    protocol,
+			Port:        port,
+		}
+	}
+
+	Context("when the rule allows all protocols", func() {
+		BeforeEach(func() {
+			rule = asg.Rule{Protocol: "all", Destination: "10.0.0.0-10.0.255.255"}
+		})
+
+		It("allows any traffic to the destination", func() {
+			Expect(rule.Allows(traffic("10.0.4.5", "tcp", 5432))).To(BeTrue())
+			Expect(rule.Allows(traffic("10.0.4.5", "", 0))).To(BeTrue())
+		})
+
+		It("does not allow traffic to other destinations", func() {
+			Expect(rule.Allows(traffic("10.1.4.5", "tcp", 5432))).To(BeFalse())
+		})
+	})
+
+	Context("when the destination range ends before it starts", func() {
+		BeforeEach(func() {
+			rule = asg.Rule{Protocol: "all", Destination: "10.0.0.9-10.0.0.1"}
+		})
+
+		It("does not allow any traffic", func() {
+			Expect(rule.Allows(traffic("10.0.0.5", "", 0))).To(BeFalse())
+			Expect(rule.Allows(traffic("10.0.0.10", "", 0))).To(BeFalse())
+		})
+	})
+
+	Context("when the rule allows specific tcp ports", func() {
+		BeforeEach(func() {
+			rule = asg.Rule{Protocol: "tcp", Destination: "10.0.0.0/16", Ports: "5432,3306"}
+		})
+
+		It("allows traffic to those ports", func() {
+			Expect(rule.Allows(traffic("10.0.4.5", "tcp", 5432))).To(BeTrue())
+			Expect(rule.Allows(traffic("10.0.4.5", "tcp", 3306))).To(BeTrue())
+		})
+
+		It("does not allow traffic to other ports or protocols", func() {
+			Expect(rule.Allows(traffic("10.0.4.5", "tcp", 80))).To(BeFalse())
+			Expect(rule.Allows(traffic("10.0.4.5", "udp", 5432))).To(BeFalse())
+		})
+	})
+
+	Context("when the rule allows a port range", func() {
+		BeforeEach(func() {
+			rule = asg.Rule{Protocol: "udp", Destination: "10.0.0.1", Ports: "1000-2000"}
+		})
+
+		It("allows traffic to ports in the range", func() {
+			Expect(rule.Allows(traffic("10.0.0.1", "udp", 1000))).To(BeTrue())
+			Expect(rule.Allows(traffic("10.0.0.1", "udp", 2000))).To(BeTrue())
+			Expect(rule.Allows(traffic("10.0.0.1", "udp", 2001))).To(BeFalse())
+		})
+	})
+
+	Context("when the rule allows icmp", func() {
+		BeforeEach(func() {
+			rule = asg.Rule{Protocol: "icmp", Destination: "10.0.0.1", Type: intPtr(8), Code: intPtr(-1)}
+		})
+
+		It("matches the type and any code", func() {
+			Expect(rule.Allows(asg.Traffic{Destination: net.ParseIP("10.0.0.1"), Protocol: "icmp", Type: intPtr(8), Code: intPtr(0)})).To(BeTrue())
+			Expect(rule.Allows(asg.Traffic{Destination: net.ParseIP("10.0.0.1"), Protocol: "icmp", Type: intPtr(0), Code: intPtr(0)})).To(BeFalse())
+		})
+	})
+})
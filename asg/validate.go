@@ -0,0 +1,301 @@
+package asg
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ProtocolAll    = "all"
+	ProtocolTCP    = "tcp"
+	ProtocolUDP    = "udp"
+	ProtocolICMP   = "icmp"
+	ProtocolICMPv6 = "icmpv6"
+)
+
+type PortRange struct {
+	Start int
+	End   int
+}
+
+func (p PortRange) Contains(port int) bool {
+	return p.Start <= port && port <= p.End
+}
+
+// ParsePorts parses a single port, a comma-separated list of ports, or a
+// hyphenated port range.
+func ParsePorts(ports string) ([]PortRange, error) {
+	if strings.Contains(ports, "-") {
+		bounds := strings.SplitN(ports, "-", 2)
+
+		start, err := parsePort(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+
+		end, err := parsePort(bounds[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if start > end {
+			return nil, fmt.Errorf("port range '%s' starts after it ends", ports)
+		}
+
+		return []PortRange{{Start: start, End: end}}, nil
+	}
+
+	var portRanges []PortRange
+	for _, p := range strings.Split(ports, ",") {
+		port, err := parsePort(p)
+		if err != nil {
+			return nil, err
+		}
+
+		portRanges = append(portRanges, PortRange{Start: port, End: port})
+	}
+
+	return portRanges, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port '%s'", s)
+	}
+
+	return port, nil
+}
+
+func (r Rule) icmp() bool {
+	return r.Protocol == ProtocolICMP || r.Protocol == ProtocolICMPv6
+}
+
+func (r Rule) Validate() []error {
+	var errs []error
+
+	if r.Destination == "" {
+		errs = append(errs, fmt.Errorf("missing destination"))
+	} else if _, err := r.DestinationRange(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid destination '%s': %s", r.Destination, err))
+	}
+
+	return append(errs, r.ValidateTraffic()...)
+}
+
+// ValidateTraffic validates everything but the destination of the rule: its
+// protocol, ports, ICMP type and code, and log setting.
+func (r Rule) ValidateTraffic() []error {
+	var errs []error
+
+	switch r.Protocol {
+	case ProtocolAll, ProtocolTCP, ProtocolUDP, ProtocolICMP, ProtocolICMPv6:
+	default:
+		errs = append(errs, fmt.Errorf("invalid protocol '%s'", r.Protocol))
+	}
+
+	switch {
+	case r.Protocol == ProtocolTCP || r.Protocol == ProtocolUDP:
+		if r.Ports == "" {
+			errs = append(errs, fmt.Errorf("ports are required for protocol '%s'", r.Protocol))
+		} else if _, err := ParsePorts(r.Ports); err != nil {
+			errs = append(errs, fmt.Errorf("invalid ports '%s': %s", r.Ports, err))
+		}
+	case r.Ports != "":
+		errs = append(errs, fmt.Errorf("ports are not allowed for protocol '%s'", r.Protocol))
+	}
+
+	if r.icmp() {
+		if r.Type == nil || r.Code == nil {
+			errs = append(errs, fmt.Errorf("type and code are required for protocol '%s'", r.Protocol))
+		}
+		if r.Type != nil && (*r.Type < -1 || *r.Type > 255) {
+			errs = append(errs, fmt.Errorf("invalid icmp type %d", *r.Type))
+		}
+		if r.Code != nil && (*r.Code < -1 || *r.Code > 255) {
+			errs = append(errs, fmt.Errorf("invalid icmp code %d", *r.Code))
+		}
+	} else if r.Type != nil || r.Code != nil {
+		errs = append(errs, fmt.Errorf("type and code are not allowed for protocol '%s'", r.Protocol))
+	}
+
+	if r.Log && r.Protocol != ProtocolTCP && r.Protocol != ProtocolAll {
+		errs = append(errs, fmt.Errorf("log is not allowed for protocol '%s'", r.Protocol))
+	}
+
+	return errs
+}
+
+// Overlaps returns true if both rules can apply to the same traffic, i.e. their
+// protocols match (or either is 'all'), their ports or ICMP types overlap and
+// their destinations overlap.
+func (r Rule) Overlaps(other Rule) bool {
+	parsed, ok := newOverlapRule(0, r)
+	if !ok {
+		return false
+	}
+
+	otherParsed, ok := newOverlapRule(1, other)
+	if !ok {
+		return false
+	}
+
+	return parsed.overlaps(otherParsed)
+}
+
+// Validate returns every problem found in the given rules, each prefixed by
+// the (1-based) position of the rule it was found in. Overlaps are found by
+// sweeping the rules in order of their destinations, so that only rules whose
+// destinations overlap are compared.
+func Validate(rules []Rule) []error {
+	var errs []error
+
+	var parsed []overlapRule
+	for i := range rules {
+		for _, err := range rules[i].Validate() {
+			errs = append(errs, fmt.Errorf("rule %d: %s", i+1, err))
+		}
+
+		if rule, ok := newOverlapRule(i, rules[i]); ok {
+			parsed = append(parsed, rule)
+		}
+	}
+
+	sort.SliceStable(parsed, func(i, j int) bool {
+		return compareIPs(parsed[i].start, parsed[j].start) < 0
+	})
+
+	var (
+		overlaps [][2]int
+		active   []overlapRule
+	)
+	for _, rule := range parsed {
+		// drop the rules that end before this one starts
+		current := active[:0]
+		for _, other := range active {
+			if compareIPs(other.end, rule.start) >= 0 {
+				current = append(current, other)
+			}
+		}
+		active = current
+
+		for _, other := range active {
+			if other.trafficOverlaps(rule) {
+				i, j := other.index, rule.index
+				if i > j {
+					i, j = j, i
+				}
+				overlaps = append(overlaps, [2]int{i, j})
+			}
+		}
+
+		active = append(active, rule)
+	}
+
+	sort.Slice(overlaps, func(a, b int) bool {
+		if overlaps[a][0] != overlaps[b][0] {
+			return overlaps[a][0] < overlaps[b][0]
+		}
+		return overlaps[a][1] < overlaps[b][1]
+	})
+
+	for _, overlap := range overlaps {
+		i, j := overlap[0], overlap[1]
+		errs = append(errs, fmt.Errorf("rule %d: destination '%s' overlaps rule %d destination '%s'", i+1, rules[i].Destination, j+1, rules[j].Destination))
+	}
+
+	return errs
+}
+
+// overlapRule is a rule with its destination and ports parsed once, for
+// finding overlapping rules. Ports that cannot be parsed overlap any ports.
+type overlapRule struct {
+	Rule
+	index      int
+	start, end net.IP
+	ports      []PortRange
+}
+
+func newOverlapRule(index int, r Rule) (overlapRule, bool) {
+	ipRange, err := r.DestinationRange()
+	if err != nil {
+		return overlapRule{}, false
+	}
+
+	start := normalizeIP(ipRange.Start)
+	end := start
+	if ipRange.End != nil {
+		end = normalizeIP(ipRange.End)
+	}
+
+	ports, _ := ParsePorts(r.Ports)
+
+	return overlapRule{Rule: r, index: index, start: start, end: end, ports: ports}, true
+}
+
+func (r overlapRule) overlaps(other overlapRule) bool {
+	return r.trafficOverlaps(other) &&
+		compareIPs(r.start, other.end) <= 0 && compareIPs(other.start, r.end) <= 0
+}
+
+// trafficOverlaps returns true if both rules can apply to the same protocol,
+// ports and ICMP type/code, regardless of their destinations.
+func (r overlapRule) trafficOverlaps(other overlapRule) bool {
+	if r.Protocol != other.Protocol && r.Protocol != ProtocolAll && other.Protocol != ProtocolAll {
+		return false
+	}
+
+	if r.Protocol == other.Protocol && !portsOverlap(r.ports, other.ports) {
+		return false
+	}
+
+	if r.Protocol == other.Protocol && r.icmp() && !(icmpOverlaps(r.Type, other.Type) && icmpOverlaps(r.Code, other.Code)) {
+		return false
+	}
+
+	return true
+}
+
+func portsOverlap(portRanges, otherPortRanges []PortRange) bool {
+	if portRanges == nil || otherPortRanges == nil {
+		return true
+	}
+
+	for _, p := range portRanges {
+		for _, o := range otherPortRanges {
+			if p.Start <= o.End && o.Start <= p.End {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func icmpOverlaps(a, b *int) bool {
+	return a == nil || b == nil || *a == -1 || *b == -1 || *a == *b
+}
+
+// normalizeIP returns the 4-byte form of IPv4 addresses, so that addresses of
+// different families never compare as equal.
+func normalizeIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+
+	return ip.To16()
+}
+
+// compareIPs orders IPv4 addresses before IPv6 addresses, and addresses of
+// the same family by value.
+func compareIPs(a, b net.IP) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+
+	return bytes.Compare(a, b)
+}
@@ -0,0 +1,26 @@
+package asg_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+)
+
+func benchmarkValidate(b *testing.B, n int) {
+	rules := make([]asg.Rule, n)
+	for i := range rules {
+		rules[i] = asg.Rule{
+			Protocol:    "all",
+			Destination: fmt.Sprintf("10.%d.%d.0-10.%d.%d.127", i/256%256, i%256, i/256%256, i%256),
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		asg.Validate(rules)
+	}
+}
+
+func BenchmarkValidate5k(b *testing.B)  { benchmarkValidate(b, 5000) }
+func BenchmarkValidate20k(b *testing.B) { benchmarkValidate(b, 20000) }
@@ -0,0 +1,167 @@
+package asg_test
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+	var (
+		rules  []asg.Rule
+		result []error
+	)
+
+	intPtr := func(i int) *int {
+		return &i
+	}
+
+	JustBeforeEach(func() {
+		result = asg.Validate(rules)
+	})
+
+	Context("when the rules are valid", func() {
+		BeforeEach(func() {
+			rules = []asg.Rule{
+				{Protocol: "all", Destination: "10.0.0.0-10.255.255.255"},
+				{Protocol: "tcp", Destination: "192.168.0.0/16", Ports: "80,443", Log: true},
+				{Protocol: "udp", Destination: "192.168.0.0/16", Ports: "53"},
+				{Protocol: "icmp", Destination: "172.16.0.1", Type: intPtr(0), Code: intPtr(-1)},
+			}
+		})
+
+		It("returns no errors", func() {
+			Expect(result).To(BeEmpty())
+		})
+	})
+
+	Context("when a destination is malformed", func() {
+		BeforeEach(func() {
+			rules = []asg.Rule{{Protocol: "all", Destination: "10.0.0.300"}}
+		})
+
+		It("returns an error", func() {
+			Expect(result).To(HaveLen(1))
+			Expect(result[0].Error()).To(HavePrefix("rule 1: invalid destination '10.0.0.300'"))
+		})
+	})
+
+	Context("when a destination range is reversed", func() {
+		BeforeEach(func() {
+			rules = []asg.Rule{{Protocol: "all", Destination: "10.0.0.9-10.0.0.1"}}
+		})
+
+		It("returns an error", func() {
+			Expect(result).To(HaveLen(1))
+			Expect(result[0].Error()).To(ContainSubstring("invalid-range-start-after-end"))
+		})
+	})
+
+	Context("when the protocol is invalid", func() {
+		BeforeEach(func() {
+			rules = []asg.Rule{{Protocol: "sctp", Destination: "10.0.0.1"}}
+		})
+
+		It("returns an error", func() {
+			Expect(result).To(HaveLen(1))
+			Expect(result[0].Error()).To(Equal("rule 1: invalid protocol 'sctp'"))
+		})
+	})
+
+	Context("when the ports are malformed", func() {
+		BeforeEach(func() {
+			rules = []asg.Rule{
+				{Protocol: "tcp", Destination: "10.0.0.1", Ports: "80-"},
+				{Protocol: "tcp", Destination: "10.0.0.2", Ports: "443-80"},
+				{Protocol: "udp", Destination: "10.0.0.3", Ports: "70000"},
+				{Protocol: "tcp", Destination: "10.0.0.4"},
+				{Protocol: "all", Destination: "10.0.0.5", Ports: "80"},
+			}
+		})
+
+		It("returns an error for each rule", func() {
+			Expect(result).To(HaveLen(5))
+			Expect(result[0].Error()).To(HavePrefix("rule 1: invalid ports '80-'"))
+			Expect(result[1].Error()).To(HavePrefix("rule 2: invalid ports '443-80'"))
+			Expect(result[2].Error()).To(HavePrefix("rule 3: invalid ports '70000'"))
+			Expect(result[3].Error()).To(Equal("rule 4: ports are required for protocol 'tcp'"))
+			Expect(result[4].Error()).To(Equal("rule 5: ports are not allowed for protocol 'all'"))
+		})
+	})
+
+	Context("when ICMP type and code are misused", func() {
+		BeforeEach(func() {
+			rules = []asg.Rule{
+				{Protocol: "icmp", Destination: "10.0.0.1"},
+				{Protocol: "icmp", Destination: "10.0.0.2", Type: intPtr(256), Code: intPtr(0)},
+				{Protocol: "tcp", Destination: "10.0.0.3", Ports: "80", Type: intPtr(0)},
+			}
+		})
+
+		It("returns an error for each rule", func() {
+			Expect(result).To(HaveLen(3))
+			Expect(result[0].Error()).To(Equal("rule 1: type and code are required for protocol 'icmp'"))
+			Expect(result[1].Error()).To(Equal("rule 2: invalid icmp type 256"))
+			Expect(result[2].Error()).To(Equal("rule 3: type and code are not allowed for protocol 'tcp'"))
+		})
+	})
+
+	Context("when rules overlap", func() {
+		BeforeEach(func() {
+			rules = []asg.Rule{
+				{Protocol: "all", Destination: "10.0.0.0/24"},
+				{Protocol: "tcp", Destination: "10.0.0.5", Ports: "443"},
+				{Protocol: "tcp", Destination: "10.0.0.0-10.0.0.10", Ports: "80"},
+				{Protocol: "udp", Destination: "10.0.1.0/24", Ports: "53"},
+			}
+		})
+
+		It("returns an error for each overlapping pair", func() {
+			Expect(result).To(HaveLen(2))
+			Expect(result[0].Error()).To(Equal("rule 1: destination '10.0.0.0/24' overlaps rule 2 destination '10.0.0.5'"))
+			Expect(result[1].Error()).To(Equal("rule 1: destination '10.0.0.0/24' overlaps rule 3 destination '10.0.0.0-10.0.0.10'"))
+		})
+	})
+
+	Context("when many unsorted rules of both address families overlap", func() {
+		BeforeEach(func() {
+			rules = nil
+			protocols := []asg.Rule{
+				{Protocol: "all"},
+				{Protocol: "tcp", Ports: "80,443"},
+				{Protocol: "tcp", Ports: "8080"},
+				{Protocol: "udp", Ports: "53"},
+			}
+			for i := 0; i < 200; i++ {
+				rule := protocols[i%len(protocols)]
+				if i%3 == 0 {
+					rule.Destination = fmt.Sprintf("2001:db8::%x-2001:db8::%x", i*37%251, i*37%251+i%7)
+				} else {
+					rule.Destination = fmt.Sprintf("10.0.%d.0-10.0.%d.255", i*53%97, i*53%97+i%3)
+				}
+				rules = append(rules, rule)
+			}
+		})
+
+		It("reports the same pairs as comparing every pair, in order", func() {
+			var expected []string
+			for i := range rules {
+				for j := i + 1; j < len(rules); j++ {
+					if rules[i].Overlaps(rules[j]) {
+						expected = append(expected, fmt.Sprintf("rule %d: destination '%s' overlaps rule %d destination '%s'", i+1, rules[i].Destination, j+1, rules[j].Destination))
+					}
+				}
+			}
+			Expect(expected).NotTo(BeEmpty())
+
+			var errs []string
+			for _, err := range result {
+				errs = append(errs, err.Error())
+			}
+			Expect(errs).To(Equal(expected))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package cc_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCc(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cc Suite")
+}
@@ -0,0 +1,294 @@
+package cc
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+)
+
+type SecurityGroup struct {
+	GUID            string          `json:"guid,omitempty"`
+	Name            string          `json:"name"`
+	Rules           []asg.Rule      `json:"rules"`
+	GloballyEnabled GloballyEnabled `json:"globally_enabled"`
+	Relationships   *Relationships  `json:"relationships,omitempty"`
+}
+
+// Relationships lists the spaces a security group is bound to. It is only
+// read; bindings are changed with BindSpaces.
+type Relationships struct {
+	RunningSpaces relationshipData `json:"running_spaces"`
+	StagingSpaces relationshipData `json:"staging_spaces"`
+}
+
+type GloballyEnabled struct {
+	Running bool `json:"running"`
+	Staging bool `json:"staging"`
+}
+
+type resource struct {
+	GUID string `json:"guid"`
+	Name string `json:"name,omitempty"`
+}
+
+type relationshipData struct {
+	Data []resource `json:"data"`
+}
+
+// SpaceGUIDs returns the GUIDs of the spaces the security group is bound to
+// for the running or staging lifecycle.
+func (sg SecurityGroup) SpaceGUIDs(lifecycle string) []string {
+	if sg.Relationships == nil {
+		return nil
+	}
+
+	data := sg.Relationships.RunningSpaces
+	if lifecycle == "staging" {
+		data = sg.Relationships.StagingSpaces
+	}
+
+	var guids []string
+	for _, r := range data.Data {
+		guids = append(guids, r.GUID)
+	}
+
+	return guids
+}
+
+type Client struct {
+	url        string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the Cloud Controller v3 API at the given URL,
+// authenticating with a UAA access token (with or without the bearer prefix).
+func NewClient(apiURL, token string, skipSSLValidation bool) *Client {
+	if !strings.HasPrefix(strings.ToLower(token), "bearer ") {
+		token = "bearer " + token
+	}
+
+	return &Client{
+		url:   strings.TrimRight(apiURL, "/"),
+		token: token,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: skipSSLValidation},
+			},
+		},
+	}
+}
+
+// GetSecurityGroup returns the security group with the given name, or nil if
+// there is none.
+func (c *Client) GetSecurityGroup(name string) (*SecurityGroup, error) {
+	var securityGroups []SecurityGroup
+	err := c.list("/v3/security_groups?names="+url.QueryEscape(name), func(bs json.RawMessage) error {
+		var sg SecurityGroup
+		if err := json.Unmarshal(bs, &sg); err != nil {
+			return err
+		}
+		securityGroups = append(securityGroups, sg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(securityGroups) == 0 {
+		return nil, nil
+	}
+
+	return &securityGroups[0], nil
+}
+
+func (c *Client) CreateSecurityGroup(sg SecurityGroup) (SecurityGroup, error) {
+	sg.GUID = ""
+	sg.Relationships = nil
+
+	var created SecurityGroup
+	err := c.do("POST", "/v3/security_groups", sg, &created)
+	return created, err
+}
+
+func (c *Client) UpdateSecurityGroup(guid string, sg SecurityGroup) (SecurityGroup, error) {
+	sg.GUID = ""
+	sg.Relationships = nil
+
+	var updated SecurityGroup
+	err := c.do("PATCH", "/v3/security_groups/"+guid, sg, &updated)
+	return updated, err
+}
+
+// BindSpaces binds the security group to the given spaces for the running or
+// staging lifecycle.
+func (c *Client) BindSpaces(securityGroupGUID, lifecycle string, spaceGUIDs []string) error {
+	data := relationshipData{Data: []resource{}}
+	for _, guid := range spaceGUIDs {
+		data.Data = append(data.Data, resource{GUID: guid})
+	}
+
+	path := fmt.Sprintf("/v3/security_groups/%s/relationships/%s_spaces", securityGroupGUID, lifecycle)
+	return c.do("POST", path, data, nil)
+}
+
+func (c *Client) GetOrganizationGUID(name string) (string, error) {
+	guids, err := c.listGUIDs("/v3/organizations?names=" + url.QueryEscape(name))
+	if err != nil {
+		return "", err
+	}
+
+	if len(guids) == 0 {
+		return "", fmt.Errorf("organization '%s' not found", name)
+	}
+
+	return guids[0], nil
+}
+
+// GetSpaceGUIDs returns the GUID of the named space in the organization, or
+// of every space in the organization if name is empty.
+func (c *Client) GetSpaceGUIDs(organizationGUID, name string) ([]string, error) {
+	path := "/v3/spaces?organization_guids=" + url.QueryEscape(organizationGUID)
+	if name != "" {
+		path += "&names=" + url.QueryEscape(name)
+	}
+
+	guids, err := c.listGUIDs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" && len(guids) == 0 {
+		return nil, fmt.Errorf("space '%s' not found", name)
+	}
+
+	return guids, nil
+}
+
+func (c *Client) listGUIDs(path string) ([]string, error) {
+	var guids []string
+	err := c.list(path, func(bs json.RawMessage) error {
+		var r resource
+		if err := json.Unmarshal(bs, &r); err != nil {
+			return err
+		}
+		guids = append(guids, r.GUID)
+		return nil
+	})
+
+	return guids, err
+}
+
+// list requests every page of a list endpoint, calling fn with each resource.
+func (c *Client) list(path string, fn func(json.RawMessage) error) error {
+	next := c.url + path
+
+	for next != "" {
+		var page struct {
+			Pagination struct {
+				Next *struct {
+					Href string `json:"href"`
+				} `json:"next"`
+			} `json:"pagination"`
+			Resources []json.RawMessage `json:"resources"`
+		}
+
+		err := c.request("GET", next, nil, &page)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range page.Resources {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+
+		next = ""
+		if page.Pagination.Next != nil {
+			next = page.Pagination.Next.Href
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) do(method, path string, body, result interface{}) error {
+	return c.request(method, c.url+path, body, result)
+}
+
+func (c *Client) request(method, requestURL string, body, result interface{}) error {
+	var bodyReader io.Reader
+	if body != nil {
+		bs, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		bodyReader = bytes.NewReader(bs)
+	}
+
+	req, err := http.NewRequest(method, requestURL, bodyReader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %s", method, requestURL, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s failed with status %d: %s", method, requestURL, resp.StatusCode, errorDetail(respBody))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	err = json.Unmarshal(respBody, result)
+	if err != nil {
+		return fmt.Errorf("failed to parse response from %s %s: %s", method, requestURL, err)
+	}
+
+	return nil
+}
+
+func errorDetail(body []byte) string {
+	var errResp struct {
+		Errors []struct {
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}
+
+	if json.Unmarshal(body, &errResp) != nil || len(errResp.Errors) == 0 {
+		return strings.TrimSpace(string(body))
+	}
+
+	var details []string
+	for _, e := range errResp.Errors {
+		details = append(details, e.Detail)
+	}
+
+	return strings.Join(details, "; ")
+}
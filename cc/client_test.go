@@ -0,0 +1,175 @@
+package cc_test
+
+import (
+	"net/http"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+	"github.com/cloudfoundry-incubator/asg-creator/cc"
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server *ghttp.Server
+		client *cc.Client
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		client = cc.NewClient(server.URL(), "some-token", false)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("GetSecurityGroup", func() {
+		It("returns the security group with the given name", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v3/security_groups", "names=some-asg"),
+				ghttp.VerifyHeaderKV("Authorization", "bearer some-token"),
+				ghttp.RespondWith(http.StatusOK, `{
+					"pagination": {"next": null},
+					"resources": [{"guid": "some-guid", "name": "some-asg", "rules": [], "globally_enabled": {"running": true, "staging": false}}]
+				}`),
+			))
+
+			sg, err := client.GetSecurityGroup("some-asg")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sg).NotTo(BeNil())
+			Expect(sg.GUID).To(Equal("some-guid"))
+			Expect(sg.GloballyEnabled.Running).To(BeTrue())
+		})
+
+		It("returns the spaces the security group is bound to", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v3/security_groups", "names=some-asg"),
+				ghttp.RespondWith(http.StatusOK, `{
+					"pagination": {"next": null},
+					"resources": [{
+						"guid": "some-guid",
+						"name": "some-asg",
+						"rules": [],
+						"relationships": {
+							"running_spaces": {"data": [{"guid": "space-1"}, {"guid": "space-2"}]},
+							"staging_spaces": {"data": []}
+						}
+					}]
+				}`),
+			))
+
+			sg, err := client.GetSecurityGroup("some-asg")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sg.SpaceGUIDs("running")).To(Equal([]string{"space-1", "space-2"}))
+			Expect(sg.SpaceGUIDs("staging")).To(BeEmpty())
+		})
+
+		It("returns nil when there is no security group with the given name", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v3/security_groups", "names=some-asg"),
+				ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": []}`),
+			))
+
+			sg, err := client.GetSecurityGroup("some-asg")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sg).To(BeNil())
+		})
+	})
+
+	Describe("CreateSecurityGroup", func() {
+		It("posts the security group", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/v3/security_groups"),
+				ghttp.VerifyJSON(`{
+					"name": "some-asg",
+					"rules": [{"protocol": "tcp", "destination": "10.0.0.0-10.0.0.255", "ports": "443"}],
+					"globally_enabled": {"running": false, "staging": true}
+				}`),
+				ghttp.RespondWith(http.StatusCreated, `{"guid": "some-guid", "name": "some-asg"}`),
+			))
+
+			sg, err := client.CreateSecurityGroup(cc.SecurityGroup{
+				Name:            "some-asg",
+				Rules:           []asg.Rule{{Protocol: "tcp", Destination: "10.0.0.0-10.0.0.255", Ports: "443"}},
+				GloballyEnabled: cc.GloballyEnabled{Staging: true},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sg.GUID).To(Equal("some-guid"))
+		})
+
+		It("returns the error details from the Cloud Controller", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusUnprocessableEntity, `{
+				"errors": [{"code": 10008, "title": "CF-UnprocessableEntity", "detail": "Rules[0]: destination must be a valid CIDR"}]
+			}`))
+
+			_, err := client.CreateSecurityGroup(cc.SecurityGroup{Name: "some-asg"})
+			Expect(err).To(MatchError(ContainSubstring("status 422: Rules[0]: destination must be a valid CIDR")))
+		})
+	})
+
+	Describe("UpdateSecurityGroup", func() {
+		It("patches the security group", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("PATCH", "/v3/security_groups/some-guid"),
+				ghttp.VerifyJSON(`{"name": "some-asg", "rules": [], "globally_enabled": {"running": true, "staging": false}}`),
+				ghttp.RespondWith(http.StatusOK, `{"guid": "some-guid", "name": "some-asg"}`),
+			))
+
+			sg, err := client.UpdateSecurityGroup("some-guid", cc.SecurityGroup{
+				Name:            "some-asg",
+				Rules:           []asg.Rule{},
+				GloballyEnabled: cc.GloballyEnabled{Running: true},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sg.GUID).To(Equal("some-guid"))
+		})
+	})
+
+	Describe("BindSpaces", func() {
+		It("posts the space relationships for the lifecycle", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/v3/security_groups/some-guid/relationships/staging_spaces"),
+				ghttp.VerifyJSON(`{"data": [{"guid": "space-1"}, {"guid": "space-2"}]}`),
+				ghttp.RespondWith(http.StatusOK, `{"data": [{"guid": "space-1"}, {"guid": "space-2"}]}`),
+			))
+
+			err := client.BindSpaces("some-guid", "staging", []string{"space-1", "space-2"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("GetSpaceGUIDs", func() {
+		It("follows pagination", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v3/spaces", "organization_guids=org-guid"),
+					ghttp.RespondWith(http.StatusOK, `{
+						"pagination": {"next": {"href": "`+server.URL()+`/v3/spaces?organization_guids=org-guid&page=2"}},
+						"resources": [{"guid": "space-1"}]
+					}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v3/spaces", "organization_guids=org-guid&page=2"),
+					ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": [{"guid": "space-2"}]}`),
+				),
+			)
+
+			guids, err := client.GetSpaceGUIDs("org-guid", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(guids).To(Equal([]string{"space-1", "space-2"}))
+		})
+
+		It("returns an error when the named space does not exist", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v3/spaces", "organization_guids=org-guid&names=some-space"),
+				ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": []}`),
+			))
+
+			_, err := client.GetSpaceGUIDs("org-guid", "some-space")
+			Expect(err).To(MatchError("space 'some-space' not found"))
+		})
+	})
+})
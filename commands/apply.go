@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cloudfoundry-incubator/asg-creator/cc"
+	"github.com/cloudfoundry-incubator/asg-creator/commands/internal/flaghelpers"
+	"github.com/cloudfoundry-incubator/asg-creator/config"
+)
+
+type ApplyCommand struct {
+	Config flaghelpers.Path `long:"config" short:"c" required:"true"`
+}
+
+func (c *ApplyCommand) Execute(args []string) error {
+	cfg, err := loadCreateConfig(string(c.Config))
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.SecurityGroups) == 0 {
+		return fmt.Errorf("config must contain security_groups to apply")
+	}
+
+	client, err := ccClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, sg := range cfg.SecurityGroups {
+		desired, err := desiredSecurityGroup(cfg, sg)
+		if err != nil {
+			return err
+		}
+
+		existing, err := client.GetSecurityGroup(sg.Name)
+		if err != nil {
+			return err
+		}
+
+		err = applySecurityGroup(client, sg, existing, desired)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("OK")
+
+	return nil
+}
+
+// ccClient returns a Cloud Controller client for the configured target, with
+// CF_API and CF_TOKEN taking precedence over the config.
+func ccClient(cfg config.Create) (*cc.Client, error) {
+	apiURL := cfg.CloudController.URL
+	if url := os.Getenv("CF_API"); url != "" {
+		apiURL = url
+	}
+	if apiURL == "" {
+		return nil, fmt.Errorf("cloud_controller.url or CF_API must be set")
+	}
+
+	token := cfg.CloudController.Token
+	if t := os.Getenv("CF_TOKEN"); t != "" {
+		token = t
+	}
+	if token == "" {
+		return nil, fmt.Errorf("cloud_controller.token or CF_TOKEN must be set")
+	}
+
+	return cc.NewClient(apiURL, token, cfg.CloudController.SkipSSLValidation), nil
+}
+
+func desiredSecurityGroup(cfg config.Create, sg config.SecurityGroup) (cc.SecurityGroup, error) {
+	rules := cfg.SecurityGroupRules(sg)
+	if len(rules) == 0 {
+		return cc.SecurityGroup{}, noRulesError(sg.Name)
+	}
+
+	return cc.SecurityGroup{
+		Name:  sg.Name,
+		Rules: rules,
+		GloballyEnabled: cc.GloballyEnabled{
+			Running: sg.Bind.Running,
+			Staging: sg.Bind.Staging,
+		},
+	}, nil
+}
+
+// applySecurityGroup creates the security group, or updates existing when it
+// is not nil, and binds it to the configured spaces.
+func applySecurityGroup(client *cc.Client, sg config.SecurityGroup, existing *cc.SecurityGroup, desired cc.SecurityGroup) error {
+	var (
+		applied cc.SecurityGroup
+		err     error
+	)
+	if existing == nil {
+		applied, err = client.CreateSecurityGroup(desired)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created security group %s\n", sg.Name)
+	} else {
+		applied, err = client.UpdateSecurityGroup(existing.GUID, desired)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Updated security group %s\n", sg.Name)
+	}
+
+	for _, binding := range sg.Bind.Spaces {
+		spaceGUIDs, err := bindingSpaceGUIDs(client, binding)
+		if err != nil {
+			return err
+		}
+
+		target := bindingTarget(binding)
+		for _, lifecycle := range binding.Lifecycles() {
+			err := client.BindSpaces(applied.GUID, lifecycle, spaceGUIDs)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Bound security group %s to %s for %s\n", sg.Name, target, lifecycle)
+		}
+	}
+
+	return nil
+}
+
+// bindingSpaceGUIDs returns the GUIDs of the spaces a space binding applies
+// to.
+func bindingSpaceGUIDs(client *cc.Client, binding config.SpaceBinding) ([]string, error) {
+	orgGUID, err := client.GetOrganizationGUID(binding.Org)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetSpaceGUIDs(orgGUID, binding.Space)
+}
+
+func bindingTarget(binding config.SpaceBinding) string {
+	if binding.Space != "" {
+		return fmt.Sprintf("space %s/%s", binding.Org, binding.Space)
+	}
+
+	return "org " + binding.Org
+}
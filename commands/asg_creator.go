@@ -1,7 +1,15 @@
 package commands
 
 type ASGCreatorCommand struct {
-	Create CreateCommand `command:"create" description:"Create default ASGs"`
+	Create    CreateCommand    `command:"create" description:"Create default ASGs"`
+	Validate  ValidateCommand  `command:"validate" description:"Validate an ASG rules file or a create config"`
+	Check     CheckCommand     `command:"check" description:"Check whether traffic to a destination is allowed by ASG rules files"`
+	Diff      DiffCommand      `command:"diff" description:"Show the address space newly allowed or denied between two ASG rules files"`
+	Apply     ApplyCommand     `command:"apply" description:"Create or update the configured security groups via the Cloud Controller API and bind them"`
+	Reconcile ReconcileCommand `command:"reconcile" description:"Report and optionally fix drift between the configured and live security groups"`
+	Lint      LintCommand      `command:"lint" description:"Check ASG rules files against a policy"`
+	Optimize  OptimizeCommand  `command:"optimize" description:"Merge the destinations of an ASG rules file into the fewest equivalent rules"`
+	Report    ReportCommand    `command:"report" description:"Summarize the address space allowed by an ASG rules file"`
 }
 
 var ASGCreator ASGCreatorCommand
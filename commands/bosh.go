@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+	"github.com/cloudfoundry-incubator/candiedyaml"
+)
+
+const opsFileName = "security-groups-ops.yml"
+
+type opsFileOperation struct {
+	Type  string     `yaml:"type"`
+	Path  string     `yaml:"path"`
+	Value []asg.Rule `yaml:"value"`
+}
+
+// opsFileBytes renders a BOSH ops file replacing the rules of each security
+// group definition, creating the definition if the manifest lacks it. Names
+// use underscores to match the cf-deployment definitions, e.g.
+// public-networks becomes public_networks.
+func opsFileBytes(securityGroups []securityGroup) ([]byte, error) {
+	ops := []opsFileOperation{}
+
+	for _, sg := range securityGroups {
+		rules := sg.rules
+		if rules == nil {
+			rules = []asg.Rule{}
+		}
+
+		ops = append(ops, opsFileOperation{
+			Type:  "replace",
+			Path:  fmt.Sprintf("/instance_groups/name=api/jobs/name=cloud_controller_ng/properties/cc/security_group_definitions/name=%s?/rules", boshName(sg.name)),
+			Value: rules,
+		})
+	}
+
+	return candiedyaml.Marshal(ops)
+}
+
+func boshName(name string) string {
+	return strings.Replace(name, "-", "_", -1)
+}
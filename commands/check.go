@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+	"github.com/cloudfoundry-incubator/asg-creator/commands/internal/flaghelpers"
+)
+
+type CheckCommand struct {
+	Rules []flaghelpers.Path `long:"rules" short:"r" required:"true" description:"ASG rules file to check against; may be given multiple times"`
+
+	Args struct {
+		Destination string `positional-arg-name:"destination" description:"Destination IP"`
+		Traffic     string `positional-arg-name:"protocol" description:"tcp/<port>, udp/<port>, icmp/<type>/<code>, icmpv6/<type>/<code> or all"`
+	} `positional-args:"yes"`
+}
+
+func (c *CheckCommand) Execute(args []string) error {
+	if c.Args.Destination == "" {
+		return fmt.Errorf("a destination IP is required")
+	}
+
+	traffic, err := parseTraffic(c.Args.Destination, c.Args.Traffic)
+	if err != nil {
+		return err
+	}
+
+	allowed := false
+	for _, path := range c.Rules {
+		rules, err := asg.LoadRules(string(path))
+		if err != nil {
+			return err
+		}
+
+		for i, rule := range rules {
+			if _, err := rule.DestinationRange(); err != nil {
+				return fmt.Errorf("%s rule %d: invalid destination '%s': %s", path, i+1, rule.Destination, err)
+			}
+
+			if !rule.Allows(traffic) {
+				continue
+			}
+
+			ruleBytes, err := json.Marshal(rule)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stdout, "Allowed by %s rule %d: %s\n", path, i+1, ruleBytes)
+			allowed = true
+		}
+	}
+
+	if !allowed {
+		return fmt.Errorf("%s is not allowed", c.trafficDescription())
+	}
+
+	fmt.Fprintln(os.Stdout, "OK")
+
+	return nil
+}
+
+func (c *CheckCommand) trafficDescription() string {
+	if c.Args.Traffic == "" {
+		return c.Args.Destination
+	}
+
+	return c.Args.Destination + " " + c.Args.Traffic
+}
+
+func parseTraffic(destination, spec string) (asg.Traffic, error) {
+	ip := net.ParseIP(destination)
+	if ip == nil {
+		return asg.Traffic{}, fmt.Errorf("invalid destination IP '%s'", destination)
+	}
+
+	traffic := asg.Traffic{Destination: ip}
+	if spec == "" || spec == asg.ProtocolAll {
+		return traffic, nil
+	}
+
+	parts := strings.Split(spec, "/")
+	traffic.Protocol = parts[0]
+
+	switch traffic.Protocol {
+	case asg.ProtocolTCP, asg.ProtocolUDP:
+		if len(parts) > 2 {
+			return asg.Traffic{}, fmt.Errorf("invalid protocol '%s', expected %s/<port>", spec, traffic.Protocol)
+		}
+
+		if len(parts) == 2 {
+			port, err := strconv.Atoi(parts[1])
+			if err != nil || port < 1 || port > 65535 {
+				return asg.Traffic{}, fmt.Errorf("invalid port '%s'", parts[1])
+			}
+			traffic.Port = port
+		}
+	case asg.ProtocolICMP, asg.ProtocolICMPv6:
+		if len(parts) > 3 {
+			return asg.Traffic{}, fmt.Errorf("invalid protocol '%s', expected %s/<type>/<code>", spec, traffic.Protocol)
+		}
+
+		values := make([]*int, 2)
+		for i, part := range parts[1:] {
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return asg.Traffic{}, fmt.Errorf("invalid icmp type or code '%s'", part)
+			}
+			values[i] = &value
+		}
+		traffic.Type, traffic.Code = values[0], values[1]
+	default:
+		return asg.Traffic{}, fmt.Errorf("invalid protocol '%s'", traffic.Protocol)
+	}
+
+	return traffic, nil
+}
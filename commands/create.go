@@ -4,82 +4,328 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/cloudfoundry-incubator/asg-creator/asg"
 	"github.com/cloudfoundry-incubator/asg-creator/commands/internal/flaghelpers"
 	"github.com/cloudfoundry-incubator/asg-creator/config"
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+)
+
+const (
+	formatJSON      = "json"
+	formatCIDR      = "cidr"
+	formatBOSH      = "bosh"
+	formatTerraform = "terraform"
+
+	outputStdout = "-"
 )
 
 type CreateCommand struct {
 	Config     flaghelpers.Path `long:"config" short:"c"`
-	OutputPath string           `long:"output" short:"o"`
+	OutputPath string           `long:"output" short:"o" description:"File to write included networks rules to, or - to write all rules to stdout"`
+	OutputDir  string           `long:"output-dir" short:"d" description:"Directory to write rules files to"`
+	Format     string           `long:"format" short:"f" default:"json" choice:"json" choice:"cidr" choice:"bosh" choice:"terraform" description:"Output format; cidr writes one rule per CIDR block instead of IP ranges, bosh writes a single BOSH ops file for cloud_controller_ng, terraform writes a single file of cloudfoundry_asg resources"`
+	MaxRules   int              `long:"max-rules" description:"Split security groups with more rules than this into numbered groups, e.g. public-networks-1, public-networks-2"`
+}
+
+type securityGroup struct {
+	name  string
+	path  string
+	rules []asg.Rule
+	bind  config.Bind
 }
 
 func (c *CreateCommand) Execute(args []string) error {
+	if c.MaxRules < 0 {
+		return fmt.Errorf("--max-rules must not be negative")
+	}
+
+	// the split groups would need binding in place of the original ones,
+	// which the bosh and terraform output cannot express
+	if c.MaxRules != 0 && (c.Format == formatBOSH || c.Format == formatTerraform) {
+		return fmt.Errorf("--max-rules cannot be used with --format %s", c.Format)
+	}
+
+	cfg, err := loadCreateConfig(string(c.Config))
+	if err != nil {
+		return err
+	}
+
+	cfg.CIDRDestinations = c.Format == formatCIDR
+
+	securityGroups, err := c.securityGroups(cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, sg := range securityGroups {
+		if len(sg.rules) == 0 {
+			return noRulesError(sg.name)
+		}
+	}
+
+	if c.MaxRules != 0 {
+		securityGroups = splitSecurityGroups(securityGroups, c.MaxRules)
+	}
+
+	// keep stdout clean for the rules when streaming them
+	ui := io.Writer(os.Stdout)
+	if c.OutputPath == outputStdout {
+		ui = os.Stderr
+	}
+
+	// the default groups exclude every non-routable special-purpose range,
+	// included networks and configured security groups only link-local
+	defaultExcluded := cfg.DefaultExcludedRanges()
+	if len(cfg.SecurityGroups) == 0 && len(cfg.Include) == 0 {
+		defaultExcluded = cfg.PublicDefaultExcludedRanges()
+	}
+
+	fmt.Fprintf(ui, "Default excludes: %s\n", rangesDescription(defaultExcluded))
+	if len(cfg.DefaultExcludes.Allow) != 0 {
+		fmt.Fprintf(ui, "Allowed within default excludes: %s\n", rangesDescription(cfg.DefaultExcludes.Allow))
+	}
+
+	switch {
+	case c.Format == formatBOSH:
+		err = c.writeSingleFile(securityGroups, opsFileName, opsFileBytes)
+	case c.Format == formatTerraform:
+		err = c.writeSingleFile(securityGroups, terraformFileName, terraformBytes)
+	case c.OutputPath == outputStdout:
+		err = writeStdout(securityGroups)
+	default:
+		err = c.writeFiles(securityGroups)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the numbered group names are not known in advance, so tell the user how
+	// to create and bind them
+	if c.MaxRules != 0 && c.OutputPath != outputStdout {
+		fmt.Fprintln(ui, "Create and bind the security groups with:")
+		for _, sg := range securityGroups {
+			for _, command := range cfCommands(sg) {
+				fmt.Fprintf(ui, "  %s\n", command)
+			}
+		}
+	}
+
+	fmt.Fprintln(ui, "OK")
+
+	return nil
+}
+
+// loadCreateConfig loads and validates the create config at path, printing
+// any problems to stderr, and adds the excludes found on the BOSH director.
+// An empty path results in the default config.
+func loadCreateConfig(path string) (config.Create, error) {
 	cfg := config.Create{}
 
-	if c.Config != "" {
+	if path != "" {
 		var err error
-		cfg, err = config.LoadCreateConfig(string(c.Config))
+		cfg, err = config.LoadCreateConfig(path)
+		if invalid, ok := err.(config.InvalidConfigError); ok {
+			for _, problem := range invalid.Problems {
+				fmt.Fprintln(os.Stderr, problem)
+			}
+			return config.Create{}, err
+		}
 		if err != nil {
-			return err
+			return config.Create{}, err
 		}
 	}
 
-	if includedNetworksRules := cfg.IncludedNetworksRules(); len(includedNetworksRules) != 0 {
-		if c.OutputPath == "" {
-			return fmt.Errorf("--output is required when config contains include")
+	if problems := cfg.Validate(); len(problems) != 0 {
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
 		}
+		return config.Create{}, fmt.Errorf("config has %d problem(s)", len(problems))
+	}
+
+	boshExcludes, err := cfg.BOSHDirectorExcludes()
+	if err != nil {
+		return config.Create{}, err
+	}
+	cfg.Exclude = append(cfg.Exclude, boshExcludes...)
+
+	return cfg, nil
+}
 
-		networkRulesBytes, err := rulesBytes(includedNetworksRules)
+func (c *CreateCommand) writeFiles(securityGroups []securityGroup) error {
+	if c.OutputDir != "" {
+		err := os.MkdirAll(c.OutputDir, 0755)
 		if err != nil {
 			return err
 		}
+	}
 
-		err = writeFile(c.OutputPath, networkRulesBytes)
+	for _, sg := range securityGroups {
+		networkRulesBytes, err := rulesBytes(sg.rules)
 		if err != nil {
 			return err
 		}
-	} else {
-		publicRulesBytes, err := rulesBytes(cfg.PublicNetworksRules())
+
+		err = writeFile(sg.path, networkRulesBytes)
 		if err != nil {
 			return err
 		}
+	}
 
-		err = writeFile("public-networks.json", publicRulesBytes)
-		if err != nil {
-			return err
+	return nil
+}
+
+// writeStdout writes the rules of a single security group as a JSON array, or
+// the rules of several security groups as a JSON object keyed by name.
+func writeStdout(securityGroups []securityGroup) error {
+	var output interface{}
+	if len(securityGroups) == 1 {
+		output = securityGroups[0].rules
+	} else {
+		rulesByName := map[string][]asg.Rule{}
+		for _, sg := range securityGroups {
+			rulesByName[sg.name] = sg.rules
 		}
+		output = rulesByName
+	}
 
-		privateRulesBytes, err := rulesBytes(cfg.PrivateNetworksRules())
-		if err != nil {
-			return err
+	bs, err := indentedJSON(output)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(os.Stdout, string(bs))
+	return err
+}
+
+// writeSingleFile renders every security group into a single document, for
+// formats such as bosh and terraform, and writes it to --output if given, or
+// to defaultName in --output-dir otherwise.
+func (c *CreateCommand) writeSingleFile(securityGroups []securityGroup, defaultName string, render func([]securityGroup) ([]byte, error)) error {
+	bs, err := render(securityGroups)
+	if err != nil {
+		return err
+	}
+
+	if c.OutputPath == outputStdout {
+		_, err = os.Stdout.Write(bs)
+		return err
+	}
+
+	path := c.OutputPath
+	if path == "" {
+		if c.OutputDir != "" {
+			err := os.MkdirAll(c.OutputDir, 0755)
+			if err != nil {
+				return err
+			}
 		}
 
-		err = writeFile("private-networks.json", privateRulesBytes)
-		if err != nil {
-			return err
+		path = filepath.Join(c.OutputDir, defaultName)
+	}
+
+	return writeFile(path, bs)
+}
+
+func (c *CreateCommand) securityGroups(cfg config.Create) ([]securityGroup, error) {
+	if len(cfg.SecurityGroups) != 0 {
+		var securityGroups []securityGroup
+		for _, sg := range cfg.SecurityGroups {
+			securityGroups = append(securityGroups, securityGroup{
+				name:  sg.Name,
+				path:  filepath.Join(c.OutputDir, sg.Name+".json"),
+				rules: cfg.SecurityGroupRules(sg),
+				bind:  sg.Bind,
+			})
 		}
+
+		return securityGroups, nil
 	}
 
-	fmt.Fprintln(os.Stdout, "OK")
+	if len(cfg.Include) != 0 {
+		if c.OutputPath == "" {
+			return nil, fmt.Errorf("--output is required when config contains include")
+		}
 
-	return nil
+		name := strings.TrimSuffix(filepath.Base(c.OutputPath), filepath.Ext(c.OutputPath))
+		if c.OutputPath == outputStdout {
+			name = "included-networks"
+		}
+
+		return []securityGroup{
+			{
+				name:  name,
+				path:  c.OutputPath,
+				rules: cfg.IncludedNetworksRules(),
+			},
+		}, nil
+	}
+
+	// the default groups are meant for default-running and default-staging
+	var securityGroups []securityGroup
+	for _, sg := range []struct {
+		name  string
+		rules []asg.Rule
+	}{
+		{"public-networks", cfg.PublicNetworksRules()},
+		{"private-networks", cfg.PrivateNetworksRules()},
+		{"public-networks-ipv6", cfg.PublicIPv6NetworksRules()},
+		{"private-networks-ipv6", cfg.PrivateIPv6NetworksRules()},
+	} {
+		securityGroups = append(securityGroups, securityGroup{
+			name:  sg.name,
+			path:  filepath.Join(c.OutputDir, sg.name+".json"),
+			rules: sg.rules,
+			bind:  config.Bind{Running: true, Staging: true},
+		})
+	}
+
+	return securityGroups, nil
+}
+
+func rangesDescription(ipRanges []iptools.IPRange) string {
+	if len(ipRanges) == 0 {
+		return "none"
+	}
+
+	var descriptions []string
+	for i := range ipRanges {
+		descriptions = append(descriptions, ipRanges[i].String())
+	}
+
+	return strings.Join(descriptions, ", ")
 }
 
 func writeFile(filepath string, filebytes []byte) error {
 	err := ioutil.WriteFile(filepath, filebytes, os.ModePerm)
 	if err != nil {
-		return fmt.Errorf(fmt.Sprintf("Failed to write private-networks.json: %s\n", err.Error()))
+		return fmt.Errorf("Failed to write %s: %s", filepath, err.Error())
 	}
 	fmt.Printf("Wrote %s\n", filepath)
 	return nil
 }
 
 func rulesBytes(rules []asg.Rule) ([]byte, error) {
-	bs, err := json.Marshal(rules)
+	if rules == nil {
+		rules = []asg.Rule{}
+	}
+
+	return indentedJSON(rules)
+}
+
+// noRulesError is returned for a security group whose included addresses are
+// all excluded, rather than silently writing or applying it without rules.
+func noRulesError(name string) error {
+	return fmt.Errorf("security group %s has no rules: every included address is excluded", name)
+}
+
+func indentedJSON(v interface{}) ([]byte, error) {
+	bs, err := json.Marshal(v)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+	"github.com/cloudfoundry-incubator/asg-creator/commands/internal/flaghelpers"
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+)
+
+type DiffCommand struct {
+	JSON bool `long:"json" description:"Print the changes as JSON"`
+
+	Args struct {
+		Old flaghelpers.Path `positional-arg-name:"old" description:"Original ASG rules file"`
+		New flaghelpers.Path `positional-arg-name:"new" description:"Updated ASG rules file"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *DiffCommand) Execute(args []string) error {
+	oldRules, err := asg.LoadRules(string(c.Args.Old))
+	if err != nil {
+		return err
+	}
+
+	newRules, err := asg.LoadRules(string(c.Args.New))
+	if err != nil {
+		return err
+	}
+
+	changes, err := asg.Diff(oldRules, newRules)
+	if err != nil {
+		return err
+	}
+
+	if c.JSON {
+		if changes == nil {
+			changes = []asg.Change{}
+		}
+
+		bs, err := indentedJSON(changes)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(os.Stdout, string(bs))
+		return nil
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(os.Stdout, "No changes")
+		return nil
+	}
+
+	for _, change := range changes {
+		fmt.Fprintf(os.Stdout, "%s: %s newly allowed, %s newly denied\n",
+			change.Traffic(), addressCount(change.Allowed), addressCount(change.Denied))
+
+		for i := range change.Allowed {
+			fmt.Fprintf(os.Stdout, "+ %s\n", change.Allowed[i].String())
+		}
+
+		for i := range change.Denied {
+			fmt.Fprintf(os.Stdout, "- %s\n", change.Denied[i].String())
+		}
+	}
+
+	return nil
+}
+
+func addressCount(ipRanges []iptools.IPRange) string {
+	total := new(big.Int)
+	for i := range ipRanges {
+		total.Add(total, ipRanges[i].Size())
+	}
+
+	return sizeDescription(total)
+}
@@ -2,8 +2,9 @@ package flaghelpers
 
 import (
 	"fmt"
-	"path/filepath"
 	"strings"
+
+	"github.com/cloudfoundry-incubator/asg-creator/paths"
 )
 
 type Path string
@@ -13,13 +14,9 @@ func (f *Path) UnmarshalFlag(value string) error {
 		return nil
 	}
 
-	matches, err := filepath.Glob(value)
+	matches, err := paths.Glob(value)
 	if err != nil {
-		return fmt.Errorf("failed to expand path '%s': %s", value, err)
-	}
-
-	if len(matches) == 0 {
-		return fmt.Errorf("path '%s' does not exist", value)
+		return err
 	}
 
 	if len(matches) > 1 {
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+	"github.com/cloudfoundry-incubator/asg-creator/commands/internal/flaghelpers"
+	"github.com/cloudfoundry-incubator/asg-creator/config"
+)
+
+type LintCommand struct {
+	Policy flaghelpers.Path `long:"policy" short:"p" required:"true" description:"Policy YAML to check the rules files against"`
+
+	Args struct {
+		Files []flaghelpers.Path `positional-arg-name:"file" description:"ASG rules files to lint"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *LintCommand) Execute(args []string) error {
+	policy, err := config.LoadPolicy(string(c.Policy))
+	if invalid, ok := err.(config.InvalidConfigError); ok {
+		for _, problem := range invalid.Problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+		return fmt.Errorf("policy has %d problem(s)", len(invalid.Problems))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse policy in %s: %s", c.Policy, err)
+	}
+
+	violations := 0
+	for _, file := range c.Args.Files {
+		rules, err := asg.LoadRules(string(file))
+		if err != nil {
+			return err
+		}
+
+		for _, violation := range policy.Lint(rules) {
+			fmt.Fprintf(os.Stdout, "%s: %s\n", file, violation)
+			violations++
+		}
+	}
+
+	if violations != 0 {
+		return fmt.Errorf("%d policy violation(s)", violations)
+	}
+
+	fmt.Fprintln(os.Stdout, "OK")
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+	"github.com/cloudfoundry-incubator/asg-creator/commands/internal/flaghelpers"
+)
+
+type OptimizeCommand struct {
+	OutputPath string `long:"output" short:"o" default:"-" description:"File to write the optimized rules to, or - to write them to stdout"`
+
+	Args struct {
+		File flaghelpers.Path `positional-arg-name:"file" description:"ASG rules file to optimize"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *OptimizeCommand) Execute(args []string) error {
+	rules, err := asg.LoadRules(string(c.Args.File))
+	if err != nil {
+		return err
+	}
+
+	optimized, groups, err := asg.Optimize(rules)
+	if err != nil {
+		return err
+	}
+
+	if optimized == nil {
+		optimized = []asg.Rule{}
+	}
+
+	// prove the optimized rules are equivalent before writing anything
+	err = checkEquivalent(rules, optimized)
+	if err != nil {
+		return err
+	}
+
+	bs, err := indentedJSON(optimized)
+	if err != nil {
+		return err
+	}
+
+	// keep stdout clean for the rules when streaming them
+	ui := io.Writer(os.Stdout)
+	if c.OutputPath == outputStdout {
+		ui = os.Stderr
+		fmt.Fprintln(os.Stdout, string(bs))
+	} else {
+		err = ioutil.WriteFile(c.OutputPath, append(bs, '\n'), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, group := range groups {
+		fmt.Fprintf(ui, "%s: %s -> %s, %s\n",
+			group.Traffic(), ruleCount(group.RulesBefore), ruleCount(group.RulesAfter), addressCount(group.Addresses.Ranges()))
+	}
+
+	fmt.Fprintf(ui, "Optimized %s to %s\n", ruleCount(len(rules)), ruleCount(len(optimized)))
+	fmt.Fprintln(ui, "Allowed address space unchanged")
+
+	if c.OutputPath != outputStdout {
+		fmt.Fprintf(ui, "Wrote %s\n", c.OutputPath)
+	}
+
+	return nil
+}
+
+// checkEquivalent verifies that both sets of rules allow the same address
+// space for every protocol, ports and ICMP type/code, and log the same part
+// of it.
+func checkEquivalent(rules, optimized []asg.Rule) error {
+	changes, err := asg.Diff(rules, optimized)
+	if err != nil {
+		return err
+	}
+
+	logChanges, err := asg.Diff(loggedRules(rules), loggedRules(optimized))
+	if err != nil {
+		return err
+	}
+
+	if len(changes) != 0 || len(logChanges) != 0 {
+		return errors.New("optimized rules are not equivalent to the original rules")
+	}
+
+	return nil
+}
+
+func loggedRules(rules []asg.Rule) []asg.Rule {
+	var logged []asg.Rule
+	for _, rule := range rules {
+		if rule.Log {
+			logged = append(logged, rule)
+		}
+	}
+	return logged
+}
+
+func ruleCount(n int) string {
+	if n == 1 {
+		return "1 rule"
+	}
+
+	return fmt.Sprintf("%d rules", n)
+}
@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+	"github.com/cloudfoundry-incubator/asg-creator/cc"
+	"github.com/cloudfoundry-incubator/asg-creator/commands/internal/flaghelpers"
+	"github.com/cloudfoundry-incubator/asg-creator/config"
+)
+
+type ReconcileCommand struct {
+	Config flaghelpers.Path `long:"config" short:"c" required:"true"`
+	Fix    bool             `long:"fix" description:"Update drifted security groups to match the config"`
+}
+
+func (c *ReconcileCommand) Execute(args []string) error {
+	cfg, err := loadCreateConfig(string(c.Config))
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.SecurityGroups) == 0 {
+		return fmt.Errorf("config must contain security_groups to reconcile")
+	}
+
+	client, err := ccClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	drifted := 0
+	for _, sg := range cfg.SecurityGroups {
+		desired, err := desiredSecurityGroup(cfg, sg)
+		if err != nil {
+			return err
+		}
+
+		live, err := client.GetSecurityGroup(sg.Name)
+		if err != nil {
+			return err
+		}
+
+		inSync, err := reportDrift(client, sg, live, desired)
+		if err != nil {
+			return err
+		}
+
+		if inSync {
+			continue
+		}
+		drifted++
+
+		if c.Fix {
+			err := applySecurityGroup(client, sg, live, desired)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if drifted != 0 && !c.Fix {
+		return fmt.Errorf("%d security group(s) have drifted", drifted)
+	}
+
+	fmt.Fprintln(os.Stdout, "OK")
+
+	return nil
+}
+
+// reportDrift prints how the live security group differs from the desired
+// one, and returns whether they are in sync. Address space is reported from
+// the point of view of the config: missing ranges are allowed by the config
+// but not live, unexpected ranges are allowed live but not by the config.
+// Logged traffic is compared separately, and only missing space bindings are
+// drift since apply never unbinds spaces.
+func reportDrift(client *cc.Client, sg config.SecurityGroup, live *cc.SecurityGroup, desired cc.SecurityGroup) (bool, error) {
+	if live == nil {
+		fmt.Fprintf(os.Stdout, "%s: security group does not exist\n", desired.Name)
+		return false, nil
+	}
+
+	changes, err := asg.Diff(live.Rules, desired.Rules)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", desired.Name, err)
+	}
+
+	logChanges, err := asg.Diff(loggedRules(live.Rules), loggedRules(desired.Rules))
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", desired.Name, err)
+	}
+
+	inSync := len(changes) == 0 && len(logChanges) == 0
+
+	printChanges(desired.Name, "", changes)
+	printChanges(desired.Name, " (log)", logChanges)
+
+	for _, lifecycle := range []struct {
+		name          string
+		live, desired bool
+	}{
+		{"running", live.GloballyEnabled.Running, desired.GloballyEnabled.Running},
+		{"staging", live.GloballyEnabled.Staging, desired.GloballyEnabled.Staging},
+	} {
+		if lifecycle.live != lifecycle.desired {
+			inSync = false
+			fmt.Fprintf(os.Stdout, "%s: globally enabled for %s is %t, expected %t\n",
+				desired.Name, lifecycle.name, lifecycle.live, lifecycle.desired)
+		}
+	}
+
+	for _, binding := range sg.Bind.Spaces {
+		spaceGUIDs, err := bindingSpaceGUIDs(client, binding)
+		if err != nil {
+			return false, fmt.Errorf("%s: %s", desired.Name, err)
+		}
+
+		for _, lifecycle := range binding.Lifecycles() {
+			missing := missingGUIDs(spaceGUIDs, live.SpaceGUIDs(lifecycle))
+			if len(missing) == 0 {
+				continue
+			}
+
+			inSync = false
+			if binding.Space != "" {
+				fmt.Fprintf(os.Stdout, "%s: not bound to %s for %s\n", desired.Name, bindingTarget(binding), lifecycle)
+			} else {
+				fmt.Fprintf(os.Stdout, "%s: not bound to %d space(s) in org %s for %s\n", desired.Name, len(missing), binding.Org, lifecycle)
+			}
+		}
+	}
+
+	if inSync {
+		fmt.Fprintf(os.Stdout, "%s: in sync\n", desired.Name)
+	}
+
+	return inSync, nil
+}
+
+func printChanges(name, suffix string, changes []asg.Change) {
+	for _, change := range changes {
+		fmt.Fprintf(os.Stdout, "%s: %s%s: %s missing, %s unexpected\n",
+			name, change.Traffic(), suffix, addressCount(change.Allowed), addressCount(change.Denied))
+
+		for i := range change.Allowed {
+			fmt.Fprintf(os.Stdout, "+ %s\n", change.Allowed[i].String())
+		}
+
+		for i := range change.Denied {
+			fmt.Fprintf(os.Stdout, "- %s\n", change.Denied[i].String())
+		}
+	}
+}
+
+func missingGUIDs(want, have []string) []string {
+	bound := map[string]bool{}
+	for _, guid := range have {
+		bound[guid] = true
+	}
+
+	var missing []string
+	for _, guid := range want {
+		if !bound[guid] {
+			missing = append(missing, guid)
+		}
+	}
+
+	return missing
+}
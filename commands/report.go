@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+	"github.com/cloudfoundry-incubator/asg-creator/commands/internal/flaghelpers"
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+)
+
+type ReportCommand struct {
+	JSON bool `long:"json" description:"Print the report as JSON"`
+	Top  int  `long:"top" default:"5" description:"Number of largest allowed blocks to show"`
+
+	Args struct {
+		File flaghelpers.Path `positional-arg-name:"file" description:"ASG rules file to report on"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *ReportCommand) Execute(args []string) error {
+	if c.Top < 0 {
+		return fmt.Errorf("--top must not be negative")
+	}
+
+	rules, err := asg.LoadRules(string(c.Args.File))
+	if err != nil {
+		return err
+	}
+
+	summary, err := asg.Summarize(rules)
+	if err != nil {
+		return err
+	}
+
+	if len(summary.LargestBlocks) > c.Top {
+		summary.LargestBlocks = summary.LargestBlocks[:c.Top]
+	}
+
+	if c.JSON {
+		bs, err := indentedJSON(summary)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(os.Stdout, string(bs))
+		return nil
+	}
+
+	fmt.Fprintf(os.Stdout, "%s: %s\n", c.Args.File, ruleCount(summary.Rules))
+
+	fmt.Fprintln(os.Stdout, "Allowed addresses by protocol:")
+	for _, protocol := range summary.Protocols {
+		fmt.Fprintf(os.Stdout, "  %s: %s, %s\n", protocol.Protocol, ruleCount(protocol.Rules), sizeDescription(protocol.Addresses))
+	}
+
+	fmt.Fprintln(os.Stdout, "Address classes:")
+	for _, class := range summary.Classes {
+		fmt.Fprintf(os.Stdout, "  %s: %s of %s allowed (%s)\n",
+			class.Name, class.Allowed, sizeDescription(class.Size), percentage(class.Allowed, class.Size, class.Fraction))
+	}
+
+	fmt.Fprintln(os.Stdout, "Largest allowed blocks:")
+	printRanges(summary.LargestBlocks)
+
+	fmt.Fprintln(os.Stdout, "Denied gaps in private space:")
+	printRanges(summary.PrivateGaps)
+
+	return nil
+}
+
+func sizeDescription(size *big.Int) string {
+	if size.Cmp(big.NewInt(1)) == 0 {
+		return "1 address"
+	}
+
+	return fmt.Sprintf("%s addresses", size)
+}
+
+// percentage formats fraction, showing a tiny but non-zero allowed share as
+// <0.01% rather than rounding it to nothing.
+func percentage(allowed, size *big.Int, fraction float64) string {
+	if allowed.Sign() != 0 && fraction < 0.0001 {
+		return "<0.01%"
+	}
+
+	if allowed.Cmp(size) < 0 && fraction > 0.9999 {
+		return ">99.99%"
+	}
+
+	return fmt.Sprintf("%.2f%%", fraction*100)
+}
+
+func printRanges(ipRanges []iptools.IPRange) {
+	if len(ipRanges) == 0 {
+		fmt.Fprintln(os.Stdout, "  none")
+		return
+	}
+
+	for i := range ipRanges {
+		fmt.Fprintf(os.Stdout, "  %s (%s)\n", ipRanges[i].String(), sizeDescription(ipRanges[i].Size()))
+	}
+}
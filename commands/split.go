@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/asg-creator/config"
+)
+
+// splitSecurityGroups splits every security group with more than maxRules
+// rules into numbered groups of at most maxRules rules each, so that
+// public-networks becomes public-networks-1, public-networks-2 and so on.
+// Together the numbered groups keep the rules, and bindings, of the original.
+func splitSecurityGroups(securityGroups []securityGroup, maxRules int) []securityGroup {
+	var split []securityGroup
+	for _, sg := range securityGroups {
+		if len(sg.rules) <= maxRules {
+			split = append(split, sg)
+			continue
+		}
+
+		ext := filepath.Ext(sg.path)
+		for i := 0; i*maxRules < len(sg.rules); i++ {
+			end := (i + 1) * maxRules
+			if end > len(sg.rules) {
+				end = len(sg.rules)
+			}
+
+			split = append(split, securityGroup{
+				name:  fmt.Sprintf("%s-%d", sg.name, i+1),
+				path:  fmt.Sprintf("%s-%d%s", strings.TrimSuffix(sg.path, ext), i+1, ext),
+				rules: sg.rules[i*maxRules : end],
+				bind:  sg.bind,
+			})
+		}
+	}
+
+	return split
+}
+
+// cfCommands returns the cf CLI commands that create a security group from its
+// rules file and bind it as configured.
+func cfCommands(sg securityGroup) []string {
+	commands := []string{
+		fmt.Sprintf("cf create-security-group %s %s", sg.name, sg.path),
+	}
+
+	if sg.bind.Running {
+		commands = append(commands, fmt.Sprintf("cf bind-running-security-group %s", sg.name))
+	}
+
+	if sg.bind.Staging {
+		commands = append(commands, fmt.Sprintf("cf bind-staging-security-group %s", sg.name))
+	}
+
+	for _, binding := range sg.bind.Spaces {
+		for _, lifecycle := range binding.Lifecycles() {
+			command := fmt.Sprintf("cf bind-security-group %s %s", sg.name, binding.Org)
+			if binding.Space != "" {
+				command += fmt.Sprintf(" --space %s", binding.Space)
+			}
+			if lifecycle == config.LifecycleStaging {
+				command += " --lifecycle staging"
+			}
+
+			commands = append(commands, command)
+		}
+	}
+
+	return commands
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const terraformFileName = "security-groups.tf"
+
+var invalidTerraformNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+type terraformAttribute struct {
+	name  string
+	value string
+}
+
+// terraformBytes renders a cloudfoundry_asg resource with one rule block per
+// rule for each security group, formatted the way terraform fmt would.
+func terraformBytes(securityGroups []securityGroup) ([]byte, error) {
+	var b bytes.Buffer
+
+	for i, sg := range securityGroups {
+		if i != 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "resource \"cloudfoundry_asg\" %s {\n", strconv.Quote(terraformName(sg.name)))
+		writeTerraformAttributes(&b, "  ", []terraformAttribute{{"name", strconv.Quote(sg.name)}})
+
+		for _, rule := range sg.rules {
+			attributes := []terraformAttribute{
+				{"protocol", strconv.Quote(rule.Protocol)},
+				{"destination", strconv.Quote(rule.Destination)},
+			}
+			if rule.Ports != "" {
+				attributes = append(attributes, terraformAttribute{"ports", strconv.Quote(rule.Ports)})
+			}
+			if rule.Type != nil {
+				attributes = append(attributes, terraformAttribute{"type", strconv.Itoa(*rule.Type)})
+			}
+			if rule.Code != nil {
+				attributes = append(attributes, terraformAttribute{"code", strconv.Itoa(*rule.Code)})
+			}
+			if rule.Log {
+				attributes = append(attributes, terraformAttribute{"log", "true"})
+			}
+
+			b.WriteString("\n  rule {\n")
+			writeTerraformAttributes(&b, "    ", attributes)
+			b.WriteString("  }\n")
+		}
+
+		b.WriteString("}\n")
+	}
+
+	return b.Bytes(), nil
+}
+
+func writeTerraformAttributes(b *bytes.Buffer, indent string, attributes []terraformAttribute) {
+	width := 0
+	for _, attribute := range attributes {
+		if len(attribute.name) > width {
+			width = len(attribute.name)
+		}
+	}
+
+	for _, attribute := range attributes {
+		fmt.Fprintf(b, "%s%s%s = %s\n", indent, attribute.name, strings.Repeat(" ", width-len(attribute.name)), attribute.value)
+	}
+}
+
+// terraformName returns a valid resource name for the security group, e.g.
+// public-networks becomes public_networks.
+func terraformName(name string) string {
+	name = invalidTerraformNameChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+
+	return name
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+	"github.com/cloudfoundry-incubator/asg-creator/commands/internal/flaghelpers"
+	"github.com/cloudfoundry-incubator/asg-creator/config"
+)
+
+type ValidateCommand struct {
+	Args struct {
+		File flaghelpers.Path `positional-arg-name:"file" description:"ASG rules JSON or create config YAML"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *ValidateCommand) Execute(args []string) error {
+	path := string(c.Args.File)
+
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var problems []error
+	if bytes.HasPrefix(bytes.TrimSpace(bs), []byte("[")) {
+		rules, err := asg.LoadRules(path)
+		if err != nil {
+			return err
+		}
+
+		problems = asg.Validate(rules)
+	} else {
+		cfg, err := config.LoadCreateConfig(path)
+		if invalid, ok := err.(config.InvalidConfigError); ok {
+			problems = invalid.Problems
+		} else if err != nil {
+			return fmt.Errorf("failed to parse config in %s: %s", path, err)
+		} else {
+			problems = cfg.Validate()
+		}
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stdout, problem)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s has %d problem(s)", path, len(problems))
+	}
+
+	fmt.Fprintln(os.Stdout, "OK")
+
+	return nil
+}
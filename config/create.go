@@ -1,24 +1,195 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
-	"net"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/cloudfoundry-incubator/asg-creator/asg"
 	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+	"github.com/cloudfoundry-incubator/asg-creator/sources"
 	"github.com/cloudfoundry-incubator/candiedyaml"
 )
 
-const protocolAll = "all"
+type Create struct {
+	Include               []iptools.IPRange `yaml:"include"`
+	Exclude               []iptools.IPRange `yaml:"exclude"`
+	IncludeFiles          []string          `yaml:"include_files"`
+	ExcludeFiles          []string          `yaml:"exclude_files"`
+	IncludeCloudRanges    []CloudRanges     `yaml:"include_cloud_ranges"`
+	ExcludeCloudRanges    []CloudRanges     `yaml:"exclude_cloud_ranges"`
+	DefaultExcludes       DefaultExcludes   `yaml:"default_excludes"`
+	ExcludeSpecialPurpose []string          `yaml:"exclude_special_purpose"`
+	AllowSpecialPurpose   []string          `yaml:"allow_special_purpose"`
+	ExcludeBOSH           BOSH              `yaml:"exclude_bosh"`
+	Protocols             []Protocol        `yaml:"protocols"`
+	SecurityGroups        []SecurityGroup   `yaml:"security_groups"`
+	CloudController       CloudController   `yaml:"cloud_controller"`
 
-var linkLocalIPRange = iptools.IPRange{
-	Start: net.IP{169, 254, 0, 0},
-	End:   net.IP{169, 254, 255, 255},
+	CIDRDestinations bool `yaml:"-"`
 }
 
-type Create struct {
-	Include []iptools.IPRange `yaml:"include"`
-	Exclude []iptools.IPRange `yaml:"exclude"`
+// DefaultExcludes controls the ranges excluded from every security group on
+// top of the configured excludes. By default these are the link-local ranges,
+// and for the public networks groups every non-routable special-purpose range,
+// such as loopback and multicast; Ranges replaces them, Allow punches holes in
+// them, and Disabled turns them off entirely.
+type DefaultExcludes struct {
+	Disabled bool              `yaml:"disabled"`
+	Ranges   []iptools.IPRange `yaml:"ranges"`
+	Allow    []iptools.IPRange `yaml:"allow"`
+}
+
+// SecurityGroup is a named set of networks to create rules for. Its excludes
+// are applied in addition to the top-level excludes, and its protocols
+// default to the top-level protocols.
+type SecurityGroup struct {
+	Name      string            `yaml:"name"`
+	Include   []iptools.IPRange `yaml:"include"`
+	Exclude   []iptools.IPRange `yaml:"exclude"`
+	Protocols []Protocol        `yaml:"protocols"`
+	Bind      Bind              `yaml:"bind"`
+}
+
+// CloudRanges selects prefixes by service and region from a cloud provider IP
+// range feed saved to File, which may be a glob.
+type CloudRanges struct {
+	Provider string   `yaml:"provider"`
+	File     string   `yaml:"file"`
+	Services []string `yaml:"services"`
+	Regions  []string `yaml:"regions"`
+}
+
+// BOSH collects VM IPs and cloud config network ranges to exclude, from a
+// director or from saved `bosh vms --json` and `bosh cloud-config` output.
+type BOSH struct {
+	Director         BOSHDirector `yaml:"director"`
+	VMsFiles         []string     `yaml:"vms_files"`
+	CloudConfigFiles []string     `yaml:"cloud_config_files"`
+	Deployments      []string     `yaml:"deployments"`
+	InstanceGroups   []string     `yaml:"instance_groups"`
+	Networks         []string     `yaml:"networks"`
+}
+
+type BOSHDirector struct {
+	URL               string `yaml:"url"`
+	Client            string `yaml:"client"`
+	ClientSecret      string `yaml:"client_secret"`
+	SkipSSLValidation bool   `yaml:"skip_ssl_validation"`
+}
+
+func (b BOSH) filter() sources.BOSHFilter {
+	return sources.BOSHFilter{
+		Deployments:    b.Deployments,
+		InstanceGroups: b.InstanceGroups,
+		Networks:       b.Networks,
+	}
+}
+
+// BOSHDirectorExcludes queries the configured BOSH director for the IPs of
+// the selected VMs and the ranges of the selected networks. BOSH_CLIENT and
+// BOSH_CLIENT_SECRET are used when the config sets no credentials.
+func (c *Create) BOSHDirectorExcludes() ([]iptools.IPRange, error) {
+	director := c.ExcludeBOSH.Director
+	if director.URL == "" {
+		return nil, nil
+	}
+
+	if director.Client == "" {
+		director.Client = os.Getenv("BOSH_CLIENT")
+		director.ClientSecret = os.Getenv("BOSH_CLIENT_SECRET")
+	}
+
+	client := &sources.BOSHDirector{
+		URL:               director.URL,
+		Client:            director.Client,
+		ClientSecret:      director.ClientSecret,
+		SkipSSLValidation: director.SkipSSLValidation,
+	}
+
+	vmIPs, err := client.VMIPs(c.ExcludeBOSH.filter())
+	if err != nil {
+		return nil, fmt.Errorf("exclude_bosh: %s", err)
+	}
+
+	networkRanges, err := client.NetworkRanges(c.ExcludeBOSH.filter())
+	if err != nil {
+		return nil, fmt.Errorf("exclude_bosh: %s", err)
+	}
+
+	return append(vmIPs, networkRanges...), nil
+}
+
+type CloudController struct {
+	URL               string `yaml:"url"`
+	Token             string `yaml:"token"`
+	SkipSSLValidation bool   `yaml:"skip_ssl_validation"`
+}
+
+// Bind describes where apply binds a security group: globally for the
+// running and staging lifecycles, and to individual spaces. A space binding
+// without a space binds every space in the org, and one without a lifecycle
+// binds for both lifecycles.
+type Bind struct {
+	Running bool           `yaml:"running"`
+	Staging bool           `yaml:"staging"`
+	Spaces  []SpaceBinding `yaml:"spaces"`
+}
+
+type SpaceBinding struct {
+	Org       string `yaml:"org"`
+	Space     string `yaml:"space"`
+	Lifecycle string `yaml:"lifecycle"`
+}
+
+const (
+	LifecycleRunning = "running"
+	LifecycleStaging = "staging"
+)
+
+// Lifecycles returns the lifecycles the space binding applies to.
+func (b SpaceBinding) Lifecycles() []string {
+	if b.Lifecycle == "" {
+		return []string{LifecycleRunning, LifecycleStaging}
+	}
+
+	return []string{b.Lifecycle}
+}
+
+type Protocol struct {
+	Protocol string `yaml:"protocol"`
+	Ports    Ports  `yaml:"ports"`
+	Type     *int   `yaml:"type"`
+	Code     *int   `yaml:"code"`
+	Log      bool   `yaml:"log"`
+}
+
+type Ports string
+
+func (p *Ports) UnmarshalYAML(tag string, value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		*p = Ports(v)
+	case int, int64:
+		*p = Ports(fmt.Sprintf("%d", v))
+	default:
+		return fmt.Errorf("failed-to-unmarshal-ports-from-value: '%v'", value)
+	}
+
+	return nil
+}
+
+func (p Protocol) rule(destination string) asg.Rule {
+	return asg.Rule{
+		Protocol:    p.Protocol,
+		Destination: destination,
+		Ports:       string(p.Ports),
+		Type:        p.Type,
+		Code:        p.Code,
+		Log:         p.Log,
+	}
 }
 
 func LoadCreateConfig(path string) (Create, error) {
@@ -28,39 +199,360 @@ func LoadCreateConfig(path string) (Create, error) {
 		return Create{}, err
 	}
 
+	if problems := rangeProblems(bs); len(problems) != 0 {
+		return Create{}, InvalidConfigError{Problems: problems}
+	}
+
 	err = candiedyaml.Unmarshal(bs, createConfig)
 	if err != nil {
 		return Create{}, err
 	}
 
+	bosh := createConfig.ExcludeBOSH
+	parseVMs := func(name string, bs []byte) ([]iptools.IPRange, []error) {
+		return withName(name)(sources.ParseVMsJSON(bs, bosh.filter()))
+	}
+	parseCloudConfig := func(name string, bs []byte) ([]iptools.IPRange, []error) {
+		return withName(name)(sources.ParseCloudConfig(bs, bosh.filter()))
+	}
+
+	var problems []error
+	for _, files := range []struct {
+		field    string
+		patterns []string
+		parse    func(string, []byte) ([]iptools.IPRange, []error)
+		ranges   *[]iptools.IPRange
+	}{
+		{"include_files", createConfig.IncludeFiles, sources.ParseRanges, &createConfig.Include},
+		{"exclude_files", createConfig.ExcludeFiles, sources.ParseRanges, &createConfig.Exclude},
+		{"exclude_bosh: vms_files", bosh.VMsFiles, parseVMs, &createConfig.Exclude},
+		{"exclude_bosh: cloud_config_files", bosh.CloudConfigFiles, parseCloudConfig, &createConfig.Exclude},
+	} {
+		ipRanges, fileProblems := loadRangeFiles(filepath.Dir(path), files.field, files.patterns, files.parse)
+		*files.ranges = append(*files.ranges, ipRanges...)
+		problems = append(problems, fileProblems...)
+	}
+
+	for _, cloud := range []struct {
+		field   string
+		entries []CloudRanges
+		ranges  *[]iptools.IPRange
+	}{
+		{"include_cloud_ranges", createConfig.IncludeCloudRanges, &createConfig.Include},
+		{"exclude_cloud_ranges", createConfig.ExcludeCloudRanges, &createConfig.Exclude},
+	} {
+		for i, entry := range cloud.entries {
+			ipRanges, entryProblems := entry.load(filepath.Dir(path), fmt.Sprintf("%s: entry %d", cloud.field, i+1))
+			*cloud.ranges = append(*cloud.ranges, ipRanges...)
+			problems = append(problems, entryProblems...)
+		}
+	}
+
+	if len(problems) != 0 {
+		return Create{}, InvalidConfigError{Problems: problems}
+	}
+
 	return *createConfig, nil
 }
 
+func (c CloudRanges) load(dir, field string) ([]iptools.IPRange, []error) {
+	if !contains(sources.Providers, c.Provider) {
+		return nil, []error{fmt.Errorf("%s: provider must be one of %s", field, strings.Join(sources.Providers, ", "))}
+	}
+
+	if c.File == "" {
+		return nil, []error{fmt.Errorf("%s: file is required", field)}
+	}
+
+	filter := sources.CloudFilter{Services: c.Services, Regions: c.Regions}
+	parse := func(name string, bs []byte) ([]iptools.IPRange, []error) {
+		return withName(name)(sources.ParseCloudRanges(c.Provider, bs, filter))
+	}
+
+	return loadRangeFiles(dir, field, []string{c.File}, parse)
+}
+
+func contains(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withName adapts a parser returning a single error to one returning problems
+// that name the file.
+func withName(name string) func([]iptools.IPRange, error) ([]iptools.IPRange, []error) {
+	return func(ipRanges []iptools.IPRange, err error) ([]iptools.IPRange, []error) {
+		if err != nil {
+			return nil, []error{fmt.Errorf("%s: %s", name, err)}
+		}
+		return ipRanges, nil
+	}
+}
+
+// loadRangeFiles parses the files matching each pattern, with relative
+// patterns resolved against the directory of the config.
+func loadRangeFiles(dir, field string, patterns []string, parse func(string, []byte) ([]iptools.IPRange, []error)) ([]iptools.IPRange, []error) {
+	var (
+		ipRanges []iptools.IPRange
+		problems []error
+	)
+
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		files, err := sources.Files(pattern)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %s", field, err))
+			continue
+		}
+
+		for _, file := range files {
+			bs, err := ioutil.ReadFile(file)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("%s: %s", field, err))
+				continue
+			}
+
+			fileRanges, fileProblems := parse(file, bs)
+			ipRanges = append(ipRanges, fileRanges...)
+			for _, problem := range fileProblems {
+				problems = append(problems, fmt.Errorf("%s: %s", field, problem))
+			}
+		}
+	}
+
+	return ipRanges, problems
+}
+
+func (c *Create) Validate() []error {
+	errs := validateRanges("include", c.Include)
+	errs = append(errs, validateRanges("exclude", c.Exclude)...)
+	errs = append(errs, validateProtocols("protocols", c.Protocols)...)
+
+	errs = append(errs, validateRanges("default_excludes: ranges", c.DefaultExcludes.Ranges)...)
+	errs = append(errs, validateRanges("default_excludes: allow", c.DefaultExcludes.Allow)...)
+
+	if c.DefaultExcludes.Disabled && len(c.DefaultExcludes.Ranges) != 0 {
+		errs = append(errs, fmt.Errorf("default_excludes: ranges cannot be used when disabled"))
+	}
+
+	for i := range c.DefaultExcludes.Allow {
+		allowed := c.DefaultExcludes.Allow[i]
+		if !overlapsAny(allowed, c.defaultExcludeRanges(iptools.NonRoutableSpecialPurposeClasses())) {
+			errs = append(errs, fmt.Errorf("default_excludes: allow: %s is not within the default excludes", allowed.String()))
+		}
+	}
+
+	for _, class := range c.ExcludeSpecialPurpose {
+		if _, err := iptools.SpecialPurposeRanges(class); err != nil {
+			errs = append(errs, fmt.Errorf("exclude_special_purpose: %s", err))
+		}
+	}
+
+	for _, class := range c.AllowSpecialPurpose {
+		if _, err := iptools.SpecialPurposeRanges(class); err != nil {
+			errs = append(errs, fmt.Errorf("allow_special_purpose: %s", err))
+		} else if contains(c.ExcludeSpecialPurpose, class) {
+			errs = append(errs, fmt.Errorf("allow_special_purpose: %s is also listed in exclude_special_purpose", class))
+		}
+	}
+
+	if len(c.SecurityGroups) != 0 && len(c.Include) != 0 {
+		errs = append(errs, fmt.Errorf("include cannot be used together with security_groups"))
+	}
+
+	names := map[string]bool{}
+	for i, sg := range c.SecurityGroups {
+		field := fmt.Sprintf("security_groups: entry %d", i+1)
+
+		switch {
+		case sg.Name == "":
+			errs = append(errs, fmt.Errorf("%s: name is required", field))
+		case strings.ContainsAny(sg.Name, `/\`):
+			errs = append(errs, fmt.Errorf("%s: invalid name '%s'", field, sg.Name))
+		case names[sg.Name]:
+			errs = append(errs, fmt.Errorf("%s: duplicate name '%s'", field, sg.Name))
+		}
+		names[sg.Name] = true
+
+		if len(sg.Include) == 0 {
+			errs = append(errs, fmt.Errorf("%s: include is required", field))
+		}
+
+		errs = append(errs, validateRanges(field+": include", sg.Include)...)
+		errs = append(errs, validateRanges(field+": exclude", sg.Exclude)...)
+		errs = append(errs, validateProtocols(field+": protocols", sg.Protocols)...)
+
+		for j, binding := range sg.Bind.Spaces {
+			bindingField := fmt.Sprintf("%s: bind: spaces: entry %d", field, j+1)
+
+			if binding.Org == "" {
+				errs = append(errs, fmt.Errorf("%s: org is required", bindingField))
+			}
+
+			switch binding.Lifecycle {
+			case "", LifecycleRunning, LifecycleStaging:
+			default:
+				errs = append(errs, fmt.Errorf("%s: invalid lifecycle '%s'", bindingField, binding.Lifecycle))
+			}
+		}
+	}
+
+	return errs
+}
+
+func overlapsAny(ipRange iptools.IPRange, ipRanges []iptools.IPRange) bool {
+	for i := range ipRanges {
+		if ipRange.OverlapsRange(ipRanges[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateRanges(field string, ipRanges []iptools.IPRange) []error {
+	var errs []error
+
+	for i := range ipRanges {
+		if err := ipRanges[i].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", field, err))
+		}
+	}
+
+	return errs
+}
+
+func validateProtocols(field string, protocols []Protocol) []error {
+	var errs []error
+
+	for i, protocol := range protocols {
+		for _, err := range protocol.rule("").ValidateTraffic() {
+			errs = append(errs, fmt.Errorf("%s: entry %d: %s", field, i+1, err))
+		}
+	}
+
+	return errs
+}
+
+// DefaultExcludedRanges returns the default excludes applied to included
+// networks and configured security groups, after removing the allowed ranges
+// from them. Only link-local is excluded from these by default.
+func (c *Create) DefaultExcludedRanges() []iptools.IPRange {
+	return c.defaultExcludedRanges([]string{iptools.SpecialPurposeLinkLocal})
+}
+
+// PublicDefaultExcludedRanges returns the default excludes applied to the
+// public networks groups, after removing the allowed ranges from them. Every
+// non-routable special-purpose class is excluded from these by default.
+func (c *Create) PublicDefaultExcludedRanges() []iptools.IPRange {
+	return c.defaultExcludedRanges(iptools.NonRoutableSpecialPurposeClasses())
+}
+
+func (c *Create) defaultExcludedRanges(defaultClasses []string) []iptools.IPRange {
+	excluded := iptools.NewIPSet(c.defaultExcludeRanges(defaultClasses)...)
+	return excluded.Difference(iptools.NewIPSet(c.DefaultExcludes.Allow...)).Ranges()
+}
+
+// defaultExcludeRanges returns the default excludes before removing the
+// allowed ranges: the default special-purpose classes that are not allowed
+// again, unless replaced or disabled, plus the special-purpose classes
+// excluded explicitly.
+func (c *Create) defaultExcludeRanges(defaultClasses []string) []iptools.IPRange {
+	var ranges []iptools.IPRange
+	var classes []string
+
+	switch {
+	case c.DefaultExcludes.Disabled:
+	case len(c.DefaultExcludes.Ranges) != 0:
+		ranges = append(ranges, c.DefaultExcludes.Ranges...)
+	case !contains(c.AllowSpecialPurpose, iptools.SpecialPurposeAll):
+		for _, class := range defaultClasses {
+			if !contains(c.AllowSpecialPurpose, class) {
+				classes = append(classes, class)
+			}
+		}
+	}
+
+	for _, class := range append(classes, c.ExcludeSpecialPurpose...) {
+		specialPurposeRanges, _ := iptools.SpecialPurposeRanges(class)
+		ranges = append(ranges, specialPurposeRanges...)
+	}
+
+	return ranges
+}
+
 func (c *Create) IncludedNetworksRules() []asg.Rule {
-	return c.rulesFromRanges(c.Include)
+	return c.rulesFromRanges(c.Include, c.DefaultExcludedRanges(), c.Protocols)
 }
 
 func (c *Create) PublicNetworksRules() []asg.Rule {
-	return c.rulesFromRanges(iptools.PublicIPRanges())
+	return c.rulesFromRanges(iptools.PublicIPRanges(), c.PublicDefaultExcludedRanges(), c.Protocols)
 }
 
 func (c *Create) PrivateNetworksRules() []asg.Rule {
-	return c.rulesFromRanges(iptools.PrivateIPRanges())
+	return c.rulesFromRanges(iptools.PrivateIPRanges(), c.DefaultExcludedRanges(), c.Protocols)
+}
+
+func (c *Create) PublicIPv6NetworksRules() []asg.Rule {
+	return c.rulesFromRanges(iptools.PublicIPv6Ranges(), c.PublicDefaultExcludedRanges(), c.Protocols)
+}
+
+func (c *Create) PrivateIPv6NetworksRules() []asg.Rule {
+	return c.rulesFromRanges(iptools.PrivateIPv6Ranges(), c.DefaultExcludedRanges(), c.Protocols)
 }
 
-func (c *Create) rulesFromRanges(baseIPRanges []iptools.IPRange) []asg.Rule {
-	excludedIPRanges := c.Exclude
-	excludedIPRanges = append(excludedIPRanges, linkLocalIPRange)
+func (c *Create) SecurityGroupRules(sg SecurityGroup) []asg.Rule {
+	protocols := sg.Protocols
+	if len(protocols) == 0 {
+		protocols = c.Protocols
+	}
+
+	excludes := append(c.DefaultExcludedRanges(), sg.Exclude...)
+	return c.rulesFromRanges(sg.Include, excludes, protocols)
+}
+
+func (c *Create) rulesFromRanges(baseIPRanges, extraExcludes []iptools.IPRange, protocols []Protocol) []asg.Rule {
+	var excludedIPRanges []iptools.IPRange
+	excludedIPRanges = append(excludedIPRanges, c.Exclude...)
+	excludedIPRanges = append(excludedIPRanges, extraExcludes...)
+
+	included := iptools.NewIPSet(baseIPRanges...).Difference(iptools.NewIPSet(excludedIPRanges...))
 
-	var rules []asg.Rule
-	for i := range baseIPRanges {
-		for _, newRange := range baseIPRanges[i].SliceRanges(excludedIPRanges) {
-			rules = append(rules, asg.Rule{
-				Destination: newRange.String(),
-				Protocol:    protocolAll,
-			})
+	var destinations []string
+	for _, includedIPRange := range included.Ranges() {
+		destinations = append(destinations, c.destinations(includedIPRange)...)
+	}
+
+	if len(protocols) == 0 {
+		protocols = []Protocol{{Protocol: asg.ProtocolAll}}
+	}
+
+	rules := []asg.Rule{}
+	for _, protocol := range protocols {
+		for _, destination := range destinations {
+			rules = append(rules, protocol.rule(destination))
 		}
 	}
 
 	return rules
 }
+
+func (c *Create) destinations(ipRange iptools.IPRange) []string {
+	if !c.CIDRDestinations {
+		return []string{ipRange.String()}
+	}
+
+	var destinations []string
+	for _, ipNet := range ipRange.CIDRs() {
+		destinations = append(destinations, ipNet.String())
+	}
+
+	return destinations
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/asg-creator/asg"
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+	"github.com/cloudfoundry-incubator/candiedyaml"
+)
+
+// Policy describes the rules files that lint accepts.
+type Policy struct {
+	MaxRules              int               `yaml:"max_rules"`
+	ForbiddenDestinations []iptools.IPRange `yaml:"forbidden_destinations"`
+	ForbiddenRules        []ForbiddenRule   `yaml:"forbidden_rules"`
+	RequireLog            []iptools.IPRange `yaml:"require_log"`
+}
+
+// ForbiddenRule forbids rules whose destinations together cover all of
+// Destination, for Protocol or for any one protocol if Protocol is empty.
+type ForbiddenRule struct {
+	Protocol    string          `yaml:"protocol"`
+	Destination iptools.IPRange `yaml:"destination"`
+}
+
+func LoadPolicy(path string) (Policy, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Policy{}, err
+	}
+
+	if problems := rangeProblems(bs); len(problems) != 0 {
+		return Policy{}, InvalidConfigError{Problems: problems}
+	}
+
+	var policy Policy
+	err = candiedyaml.Unmarshal(bs, &policy)
+	if err != nil {
+		return Policy{}, err
+	}
+
+	for i, forbidden := range policy.ForbiddenRules {
+		if forbidden.Destination.Start == nil {
+			return Policy{}, fmt.Errorf("forbidden_rules: entry %d: destination is required", i+1)
+		}
+	}
+
+	return policy, nil
+}
+
+// Lint returns a violation for each way the rules break the policy. Rules
+// reaching a require_log range are only required to log if their protocol
+// supports logging.
+func (p Policy) Lint(rules []asg.Rule) []error {
+	var violations []error
+
+	if p.MaxRules > 0 && len(rules) > p.MaxRules {
+		violations = append(violations, fmt.Errorf("%d rules exceed the maximum of %d", len(rules), p.MaxRules))
+	}
+
+	destinations := make([]*iptools.IPRange, len(rules))
+	for i, rule := range rules {
+		if destination, err := rule.DestinationRange(); err == nil {
+			destinations[i] = &destination
+		}
+	}
+
+	// forbidden rule violations are reported with the first of the rules
+	// that together allow the forbidden traffic
+	forbiddenRuleViolations := map[int][]error{}
+	for _, forbidden := range p.ForbiddenRules {
+		indexes := forbidden.coveringRules(rules, destinations)
+		if len(indexes) == 0 {
+			continue
+		}
+
+		protocol := forbidden.Protocol
+		if protocol == "" {
+			protocol = "any protocol"
+		}
+
+		var violation error
+		if len(indexes) == 1 {
+			violation = fmt.Errorf("rule %d: destination '%s' allows all of %s with %s", indexes[0]+1, rules[indexes[0]].Destination, forbidden.Destination.String(), protocol)
+		} else {
+			violation = fmt.Errorf("%s: destinations together allow all of %s with %s", ruleNumbers(indexes), forbidden.Destination.String(), protocol)
+		}
+		forbiddenRuleViolations[indexes[0]] = append(forbiddenRuleViolations[indexes[0]], violation)
+	}
+
+	for i, rule := range rules {
+		if destinations[i] == nil {
+			_, err := rule.DestinationRange()
+			violations = append(violations, fmt.Errorf("rule %d: invalid destination '%s': %s", i+1, rule.Destination, err))
+			continue
+		}
+		destination := *destinations[i]
+
+		for j := range p.ForbiddenDestinations {
+			forbidden := p.ForbiddenDestinations[j]
+			if destination.OverlapsRange(forbidden) {
+				violations = append(violations, fmt.Errorf("rule %d: destination '%s' reaches forbidden destination %s", i+1, rule.Destination, forbidden.String()))
+			}
+		}
+
+		violations = append(violations, forbiddenRuleViolations[i]...)
+
+		if rule.Log || (rule.Protocol != asg.ProtocolTCP && rule.Protocol != asg.ProtocolAll) {
+			continue
+		}
+
+		for j := range p.RequireLog {
+			required := p.RequireLog[j]
+			if destination.OverlapsRange(required) {
+				violations = append(violations, fmt.Errorf("rule %d: destination '%s' reaches %s without log", i+1, rule.Destination, required.String()))
+				break
+			}
+		}
+	}
+
+	return violations
+}
+
+// coveringRules returns the indexes of the rules whose destinations together
+// cover all of the forbidden destination for the forbidden protocol, or for
+// any one protocol if none is given, preferring a single rule covering all of
+// it. Rules for all protocols allow the traffic of every protocol.
+func (f ForbiddenRule) coveringRules(rules []asg.Rule, destinations []*iptools.IPRange) []int {
+	protocols := []string{f.Protocol}
+	if f.Protocol == "" {
+		protocols = []string{asg.ProtocolAll}
+		for _, rule := range rules {
+			if !contains(protocols, rule.Protocol) {
+				protocols = append(protocols, rule.Protocol)
+			}
+		}
+	}
+
+	for _, protocol := range protocols {
+		var (
+			indexes  []int
+			ipRanges []iptools.IPRange
+		)
+		for i, rule := range rules {
+			if destinations[i] == nil || (rule.Protocol != protocol && rule.Protocol != asg.ProtocolAll) {
+				continue
+			}
+
+			if !destinations[i].OverlapsRange(f.Destination) {
+				continue
+			}
+
+			if iptools.NewIPSet(f.Destination).Difference(iptools.NewIPSet(*destinations[i])).Empty() {
+				return []int{i}
+			}
+
+			indexes = append(indexes, i)
+			ipRanges = append(ipRanges, *destinations[i])
+		}
+
+		if len(indexes) != 0 && iptools.NewIPSet(f.Destination).Difference(iptools.NewIPSet(ipRanges...)).Empty() {
+			return indexes
+		}
+	}
+
+	return nil
+}
+
+// ruleNumbers describes the rules at the given indexes, e.g. "rules 1, 2 and
+// 5", listing at most five of them.
+func ruleNumbers(indexes []int) string {
+	const maxListed = 5
+
+	var numbers []string
+	for _, i := range indexes {
+		if len(numbers) == maxListed {
+			break
+		}
+		numbers = append(numbers, fmt.Sprintf("%d", i+1))
+	}
+
+	if len(indexes) > maxListed {
+		return fmt.Sprintf("rules %s and %d more", strings.Join(numbers, ", "), len(indexes)-maxListed)
+	}
+
+	return fmt.Sprintf("rules %s and %s", strings.Join(numbers[:len(numbers)-1], ", "), numbers[len(numbers)-1])
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+)
+
+// InvalidConfigError is returned by LoadCreateConfig when entries in the
+// config are invalid. Each problem names the line it was found on.
+type InvalidConfigError struct {
+	Problems []error
+}
+
+func (e InvalidConfigError) Error() string {
+	return fmt.Sprintf("config has %d problem(s)", len(e.Problems))
+}
+
+// rangeFields are the keys whose values are lists of IPs, CIDRs and IP
+// ranges, at any level of a create config or policy.
+var rangeFields = map[string]bool{
+	"include":                true,
+	"exclude":                true,
+	"ranges":                 true,
+	"allow":                  true,
+	"forbidden_destinations": true,
+	"require_log":            true,
+}
+
+var (
+	keyLinePattern  = regexp.MustCompile(`^(\s*)(?:-\s+)?([A-Za-z_]+):(?:\s+(.*))?$`)
+	itemLinePattern = regexp.MustCompile(`^(\s*)-\s+(.*)$`)
+)
+
+// rangeProblems checks every range entry in the YAML document, returning a
+// problem with the line number for each one that cannot be parsed, ends before
+// it starts, or mixes address families. Both block and flow sequences are
+// recognized, and flow sequences may span several lines.
+func rangeProblems(bs []byte) []error {
+	var (
+		problems  []error
+		field     string
+		lineNum   int
+		keyIndent int
+		inFlow    bool
+	)
+
+	check := func(value string) {
+		value = unquote(value)
+		if value == "" {
+			return
+		}
+
+		ipRange, err := iptools.ParseIPRange(value)
+		if err == nil {
+			err = ipRange.Validate()
+		}
+		if err != nil {
+			problems = append(problems, fmt.Errorf("line %d: %s: %s", lineNum, field, err))
+		}
+	}
+
+	// checkFlow checks the entries of a flow sequence on the current line,
+	// which may open or close the sequence.
+	checkFlow := func(value string) {
+		value = strings.TrimPrefix(value, "[")
+		if idx := strings.Index(value, "]"); idx != -1 {
+			value = value[:idx]
+			inFlow = false
+		}
+
+		for _, entry := range strings.Split(value, ",") {
+			check(strings.TrimSpace(entry))
+		}
+
+		if !inFlow {
+			field = ""
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	for scanner.Scan() {
+		lineNum++
+		line := stripComment(scanner.Text())
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if inFlow {
+			checkFlow(strings.TrimSpace(line))
+			continue
+		}
+
+		if match := keyLinePattern.FindStringSubmatch(line); match != nil {
+			field = ""
+			if !rangeFields[match[2]] {
+				continue
+			}
+
+			value := strings.TrimSpace(match[3])
+			if value == "" {
+				field = match[2]
+				keyIndent = len(match[1])
+				continue
+			}
+
+			if strings.HasPrefix(value, "[") {
+				field = match[2]
+				inFlow = true
+				checkFlow(value)
+			}
+			continue
+		}
+
+		if value := strings.TrimSpace(line); field != "" && strings.HasPrefix(value, "[") {
+			inFlow = true
+			checkFlow(value)
+			continue
+		}
+
+		match := itemLinePattern.FindStringSubmatch(line)
+		if match == nil || field == "" {
+			continue
+		}
+
+		if len(match[1]) < keyIndent {
+			field = ""
+			continue
+		}
+
+		check(strings.TrimSpace(match[2]))
+	}
+
+	return problems
+}
+
+func stripComment(line string) string {
+	if idx := strings.Index(line, " #"); idx != -1 {
+		return line[:idx]
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return ""
+	}
+
+	return line
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
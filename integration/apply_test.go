@@ -0,0 +1,193 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Apply", func() {
+	var (
+		server     *ghttp.Server
+		configFile *os.File
+		config     string
+		sess       *gexec.Session
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+
+		config = `
+security_groups:
+- name: dns
+  include:
+  - 10.0.0.2
+  protocols:
+  - protocol: udp
+    ports: 53
+  bind:
+    running: true
+    staging: true
+- name: db
+  include:
+  - 10.0.1.0/24
+  protocols:
+  - protocol: tcp
+    ports: 5432
+  bind:
+    spaces:
+    - org: some-org
+      space: some-space
+      lifecycle: running
+`
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		configFile, err = ioutil.TempFile("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(configFile.Name(), []byte(config), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		cmd := exec.Command(binPath, "apply", "--config", configFile.Name())
+		cmd.Env = append(os.Environ(), "CF_API="+server.URL(), "CF_TOKEN=some-token")
+		sess, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(configFile.Name())
+	})
+
+	Context("when one security group exists and the other does not", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v3/security_groups", "names=dns"),
+					ghttp.VerifyHeaderKV("Authorization", "bearer some-token"),
+					ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": [{"guid": "dns-guid", "name": "dns"}]}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PATCH", "/v3/security_groups/dns-guid"),
+					ghttp.VerifyJSON(`{
+						"name": "dns",
+						"rules": [{"protocol": "udp", "destination": "10.0.0.2", "ports": "53"}],
+						"globally_enabled": {"running": true, "staging": true}
+					}`),
+					ghttp.RespondWith(http.StatusOK, `{"guid": "dns-guid", "name": "dns"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v3/security_groups", "names=db"),
+					ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": []}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/v3/security_groups"),
+					ghttp.VerifyJSON(`{
+						"name": "db",
+						"rules": [{"protocol": "tcp", "destination": "10.0.1.0-10.0.1.255", "ports": "5432"}],
+						"globally_enabled": {"running": false, "staging": false}
+					}`),
+					ghttp.RespondWith(http.StatusCreated, `{"guid": "db-guid", "name": "db"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v3/organizations", "names=some-org"),
+					ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": [{"guid": "org-guid"}]}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v3/spaces", "organization_guids=org-guid&names=some-space"),
+					ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": [{"guid": "space-guid"}]}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/v3/security_groups/db-guid/relationships/running_spaces"),
+					ghttp.VerifyJSON(`{"data": [{"guid": "space-guid"}]}`),
+					ghttp.RespondWith(http.StatusOK, `{"data": [{"guid": "space-guid"}]}`),
+				),
+			)
+		})
+
+		It("updates the existing group, creates the new one and binds it", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say("Updated security group dns"))
+			Expect(sess.Out).To(gbytes.Say("Created security group db"))
+			Expect(sess.Out).To(gbytes.Say("Bound security group db to space some-org/some-space for running"))
+			Expect(sess.Out).To(gbytes.Say("OK"))
+			Expect(server.ReceivedRequests()).To(HaveLen(7))
+		})
+	})
+
+	Context("when the Cloud Controller rejects a request", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusUnauthorized, `{"errors": [{"code": 1000, "title": "CF-InvalidAuthToken", "detail": "Invalid Auth Token"}]}`),
+			)
+		})
+
+		It("exits with the error details", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("Invalid Auth Token"))
+		})
+	})
+
+	Context("when the config does not contain security groups", func() {
+		BeforeEach(func() {
+			config = `
+include:
+- 10.0.0.0/24
+`
+		})
+
+		It("exits with an error", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("config must contain security_groups to apply"))
+			Expect(server.ReceivedRequests()).To(BeEmpty())
+		})
+	})
+
+	Context("when every address a security group includes is excluded", func() {
+		BeforeEach(func() {
+			config = `
+security_groups:
+- name: metadata
+  include:
+  - 169.254.169.254
+`
+		})
+
+		It("exits with an error without contacting the Cloud Controller", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("security group metadata has no rules: every included address is excluded"))
+			Expect(server.ReceivedRequests()).To(BeEmpty())
+		})
+	})
+
+	Context("when a space binding has an invalid lifecycle", func() {
+		BeforeEach(func() {
+			config = `
+security_groups:
+- name: db
+  include:
+  - 10.0.1.0/24
+  bind:
+    spaces:
+    - org: some-org
+      lifecycle: deploying
+`
+		})
+
+		It("exits with an error without contacting the Cloud Controller", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("security_groups: entry 1: bind: spaces: entry 1: invalid lifecycle 'deploying'"))
+			Expect(server.ReceivedRequests()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,103 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Check", func() {
+	var (
+		rulesFile *os.File
+		args      []string
+		sess      *gexec.Session
+	)
+
+	BeforeEach(func() {
+		var err error
+		rulesFile, err = ioutil.TempFile("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(rulesFile.Name(), []byte(`[
+			{"protocol": "all", "destination": "10.1.0.0-10.1.255.255"},
+			{"protocol": "tcp", "destination": "10.0.4.0/24", "ports": "5432"}
+		]`), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		sess, err = gexec.Start(exec.Command(binPath, append([]string{"check", "--rules", rulesFile.Name()}, args...)...), GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(rulesFile.Name())
+	})
+
+	Context("when the traffic is allowed", func() {
+		BeforeEach(func() {
+			args = []string{"10.0.4.5", "tcp/5432"}
+		})
+
+		It("prints the rule that allows it", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say(`rule 2: {"protocol":"tcp","destination":"10.0.4.0/24","ports":"5432"}`))
+		})
+	})
+
+	Context("when a destination range ends before it starts", func() {
+		BeforeEach(func() {
+			err := ioutil.WriteFile(rulesFile.Name(), []byte(`[
+				{"protocol": "all", "destination": "10.0.0.9-10.0.0.1"}
+			]`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			args = []string{"10.0.0.5"}
+		})
+
+		It("exits with an error", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("rule 1: invalid destination '10.0.0.9-10.0.0.1': invalid-range-start-after-end"))
+		})
+	})
+
+	Context("when the traffic is not allowed", func() {
+		BeforeEach(func() {
+			args = []string{"10.0.4.5", "udp/5432"}
+		})
+
+		It("exits non-zero", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("10.0.4.5 udp/5432 is not allowed"))
+		})
+	})
+
+	Context("when no protocol is given", func() {
+		BeforeEach(func() {
+			args = []string{"10.1.2.3"}
+		})
+
+		It("checks any traffic to the destination", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say(`rule 1: {"protocol":"all","destination":"10.1.0.0-10.1.255.255"}`))
+		})
+	})
+
+	Context("when given an invalid protocol", func() {
+		BeforeEach(func() {
+			args = []string{"10.1.2.3", "sctp/80"}
+		})
+
+		It("exits non-zero", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("invalid protocol 'sctp'"))
+		})
+	})
+})
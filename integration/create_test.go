@@ -1,10 +1,12 @@
 package integration_test
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"os/exec"
 
+	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/gexec"
 
 	. "github.com/onsi/ginkgo"
@@ -17,6 +19,8 @@ var _ = Describe("Create", func() {
 	AfterEach(func() {
 		os.RemoveAll("public-networks.json")
 		os.RemoveAll("private-networks.json")
+		os.RemoveAll("public-networks-ipv6.json")
+		os.RemoveAll("private-networks-ipv6.json")
 	})
 
 	Context("when not given a config", func() {
@@ -39,28 +43,60 @@ var _ = Describe("Create", func() {
 			Expect(bs).To(MatchJSON([]byte(`
 				[
 					{
-						"destination": "0.0.0.0-9.255.255.255",
-						"protocol": "all"
+						"protocol": "all",
+						"destination": "1.0.0.0-9.255.255.255"
+					},
+					{
+						"protocol": "all",
+						"destination": "11.0.0.0-100.63.255.255"
+					},
+					{
+						"protocol": "all",
+						"destination": "100.128.0.0-126.255.255.255"
+					},
+					{
+						"protocol": "all",
+						"destination": "128.0.0.0-169.253.255.255"
+					},
+					{
+						"protocol": "all",
+						"destination": "169.255.0.0-172.15.255.255"
+					},
+					{
+						"protocol": "all",
+						"destination": "172.32.0.0-192.0.1.255"
 					},
 					{
-						"destination": "11.0.0.0-169.253.255.255",
-						"protocol": "all"
+						"protocol": "all",
+						"destination": "192.0.3.0-192.167.255.255"
+					},
+					{
+						"protocol": "all",
+						"destination": "192.169.0.0-198.17.255.255"
 					},
 					{
-						"destination": "169.255.0.0-172.15.255.255",
-						"protocol": "all"
+						"protocol": "all",
+						"destination": "198.20.0.0-198.51.99.255"
 					},
 					{
-						"destination": "172.32.0.0-192.167.255.255",
-						"protocol": "all"
+						"protocol": "all",
+						"destination": "198.51.101.0-203.0.112.255"
 					},
 					{
-						"destination": "192.169.0.0-255.255.255.255",
-						"protocol": "all"
+						"protocol": "all",
+						"destination": "203.0.114.0-223.255.255.255"
 					}
 				]`)))
 		})
 
+		It("reports the non-routable special-purpose ranges as default excludes", func() {
+			sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say(`Default excludes: 0\.0\.0\.0-0\.255\.255\.255, 100\.64\.0\.0-100\.127\.255\.255, 127\.0\.0\.0-127\.255\.255\.255, 169\.254\.0\.0-169\.254\.255\.255, .*, 224\.0\.0\.0-255\.255\.255\.255, ::-::1, .*, ff00::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff\n`))
+		})
+
 		It("writes private-networks.json", func() {
 			sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
 			Expect(err).NotTo(HaveOccurred())
@@ -90,6 +126,65 @@ var _ = Describe("Create", func() {
 				]`)))
 		})
 
+		It("writes public-networks-ipv6.json", func() {
+			sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(sess).Should(gexec.Exit(0))
+
+			bs, err := ioutil.ReadFile("public-networks-ipv6.json")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(bs).To(MatchJSON([]byte(`
+				[
+					{
+						"protocol": "all",
+						"destination": "::2-::fffe:ffff:ffff"
+					},
+					{
+						"protocol": "all",
+						"destination": "::1:0:0:0-ff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"
+					},
+					{
+						"protocol": "all",
+						"destination": "100:0:0:1::-2001:1:ffff:ffff:ffff:ffff:ffff:ffff"
+					},
+					{
+						"protocol": "all",
+						"destination": "2001:2:1::-2001:db7:ffff:ffff:ffff:ffff:ffff:ffff"
+					},
+					{
+						"protocol": "all",
+						"destination": "2001:db9::-fbff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"
+					},
+					{
+						"protocol": "all",
+						"destination": "fe00::-fe7f:ffff:ffff:ffff:ffff:ffff:ffff:ffff"
+					},
+					{
+						"protocol": "all",
+						"destination": "fec0::-feff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"
+					}
+				]`)))
+		})
+
+		It("writes private-networks-ipv6.json", func() {
+			sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(sess).Should(gexec.Exit(0))
+
+			bs, err := ioutil.ReadFile("private-networks-ipv6.json")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(bs).To(MatchJSON([]byte(`
+				[
+					{
+						"protocol": "all",
+						"destination": "fc00::-fdff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"
+					}
+				]`)))
+		})
 	})
 
 	Context("when given a config", func() {
@@ -177,7 +272,7 @@ exclude:
 					[
 						{
 							"protocol": "all",
-							"destination": "0.0.0.0-9.255.255.255"
+							"destination": "1.0.0.0-9.255.255.255"
 						},
 						{
 							"protocol": "all",
@@ -185,7 +280,15 @@ exclude:
 						},
 						{
 							"protocol": "all",
-							"destination": "11.0.2.0-169.253.255.255"
+							"destination": "11.0.2.0-100.63.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "100.128.0.0-126.255.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "128.0.0.0-169.253.255.255"
 						},
 						{
 							"protocol": "all",
@@ -193,11 +296,27 @@ exclude:
 						},
 						{
 							"protocol": "all",
-							"destination": "172.32.0.0-192.167.255.255"
+							"destination": "172.32.0.0-192.0.1.255"
 						},
 						{
 							"protocol": "all",
-							"destination": "192.169.0.0-255.255.255.255"
+							"destination": "192.0.3.0-192.167.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "192.169.0.0-198.17.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "198.20.0.0-198.51.99.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "198.51.101.0-203.0.112.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "203.0.114.0-223.255.255.255"
 						}
 					]`)))
 			})
@@ -274,32 +393,56 @@ exclude:
 				Expect(bs).To(MatchJSON([]byte(`
 					[
 						{
-							"destination": "0.0.0.0-9.255.255.255",
-							"protocol": "all"
+							"protocol": "all",
+							"destination": "1.0.0.0-9.255.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "11.0.0.0-11.0.0.4"
+						},
+						{
+							"protocol": "all",
+							"destination": "11.0.0.6-11.0.0.7"
+						},
+						{
+							"protocol": "all",
+							"destination": "11.0.0.9-100.63.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "100.128.0.0-126.255.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "128.0.0.0-169.253.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "169.255.0.0-172.15.255.255"
 						},
 						{
-							"destination": "11.0.0.0-11.0.0.4",
-							"protocol": "all"
+							"protocol": "all",
+							"destination": "172.32.0.0-192.0.1.255"
 						},
 						{
-							"destination": "11.0.0.6-11.0.0.7",
-							"protocol": "all"
+							"protocol": "all",
+							"destination": "192.0.3.0-192.167.255.255"
 						},
 						{
-							"destination": "11.0.0.9-169.253.255.255",
-							"protocol": "all"
+							"protocol": "all",
+							"destination": "192.169.0.0-198.17.255.255"
 						},
 						{
-							"destination": "169.255.0.0-172.15.255.255",
-							"protocol": "all"
+							"protocol": "all",
+							"destination": "198.20.0.0-198.51.99.255"
 						},
 						{
-							"destination": "172.32.0.0-192.167.255.255",
-							"protocol": "all"
+							"protocol": "all",
+							"destination": "198.51.101.0-203.0.112.255"
 						},
 						{
-							"destination": "192.169.0.0-255.255.255.255",
-							"protocol": "all"
+							"protocol": "all",
+							"destination": "203.0.114.0-223.255.255.255"
 						}
 					]`)))
 			})
@@ -352,139 +495,632 @@ exclude:
 			})
 		})
 
-		Context("when the config is such that it expects a rule with a single IP", func() {
+		Context("when the config contains IPv6 networks to exclude", func() {
 			BeforeEach(func() {
 				config = `
 exclude:
-- 192.168.100.4
-- 192.168.100.6
+- fd00::/8
+- fc00::5
 `
 			})
 
-			It("should create a rule with a single IP", func() {
+			It("should omit them in the private-networks-ipv6 ASG", func() {
 				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
 				Expect(err).NotTo(HaveOccurred())
 
 				Eventually(sess).Should(gexec.Exit(0))
 
-				_, err = os.Lstat("private-networks.json")
+				bs, err := ioutil.ReadFile("private-networks-ipv6.json")
 				Expect(err).NotTo(HaveOccurred())
 
-				bs, err := ioutil.ReadFile("private-networks.json")
+				Expect(bs).To(MatchJSON([]byte(`
+					[
+						{
+							"protocol": "all",
+							"destination": "fc00::-fc00::4"
+						},
+						{
+							"protocol": "all",
+							"destination": "fc00::6-fcff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"
+						}
+					]`)))
+			})
+		})
+
+		Context("when the config excludes special-purpose ranges", func() {
+			BeforeEach(func() {
+				config = `
+exclude_special_purpose:
+- 6to4-relay-anycast
+- ietf-protocol-assignments
+`
+			})
+
+			It("should omit them in the public-networks ASG", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(sess).Should(gexec.Exit(0))
+
+				bs, err := ioutil.ReadFile("public-networks.json")
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(bs).To(MatchJSON([]byte(`
 					[
 						{
 							"protocol": "all",
-							"destination": "10.0.0.0-10.255.255.255"
+							"destination": "1.0.0.0-9.255.255.255"
 						},
 						{
 							"protocol": "all",
-							"destination": "172.16.0.0-172.31.255.255"
+							"destination": "11.0.0.0-100.63.255.255"
 						},
 						{
 							"protocol": "all",
-							"destination": "192.168.0.0-192.168.100.3"
+							"destination": "100.128.0.0-126.255.255.255"
 						},
 						{
 							"protocol": "all",
-							"destination": "192.168.100.5"
+							"destination": "128.0.0.0-169.253.255.255"
 						},
 						{
 							"protocol": "all",
-							"destination": "192.168.100.7-192.168.255.255"
+							"destination": "169.255.0.0-172.15.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "172.32.0.0-191.255.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "192.0.1.0-192.0.1.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "192.0.3.0-192.88.98.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "192.88.100.0-192.167.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "192.169.0.0-198.17.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "198.20.0.0-198.51.99.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "198.51.101.0-203.0.112.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "203.0.114.0-223.255.255.255"
 						}
 					]`)))
 			})
 		})
-	})
-
-	Context("when given a config and an output file", func() {
-		var configFile *os.File
-		var outputFile *os.File
-		var config string
-
-		JustBeforeEach(func() {
-			var err error
-			configFile, err = ioutil.TempFile("", "")
-			Expect(err).NotTo(HaveOccurred())
 
-			err = ioutil.WriteFile(configFile.Name(), []byte(config), os.ModePerm)
-			Expect(err).NotTo(HaveOccurred())
+		Context("when the config allows special-purpose classes", func() {
+			BeforeEach(func() {
+				config = `
+allow_special_purpose:
+- this-network
+- shared-address-space
+- loopback
+`
+			})
 
-			outputFile, err = ioutil.TempFile("", "")
-			Expect(err).NotTo(HaveOccurred())
+			It("should include them in the public-networks ASG", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
 
-			cmd = exec.Command(binPath, "create", "--config", configFile.Name(), "--output", outputFile.Name())
-		})
+				Eventually(sess).Should(gexec.Exit(0))
+				Expect(sess.Out).To(gbytes.Say(`Default excludes: 169\.254\.0\.0-169\.254\.255\.255, `))
 
-		AfterEach(func() {
-			os.RemoveAll(configFile.Name())
-			os.RemoveAll(outputFile.Name())
+				var rules []map[string]interface{}
+				bs, err := ioutil.ReadFile("public-networks.json")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(json.Unmarshal(bs, &rules)).To(Succeed())
+				Expect(rules[0]["destination"]).To(Equal("0.0.0.0-9.255.255.255"))
+				Expect(rules[1]["destination"]).To(Equal("11.0.0.0-169.253.255.255"))
+			})
 		})
 
-		Context("when the config contains networks to include", func() {
+		Context("when the config both excludes and allows a special-purpose class", func() {
 			BeforeEach(func() {
 				config = `
-include:
-- 10.68.192.0/24
-
-exclude:
-- 10.68.192.0
-- 10.68.192.127
-- 10.68.192.128
-- 10.68.192.255
+exclude_special_purpose:
+- 6to4-relay-anycast
+allow_special_purpose:
+- 6to4-relay-anycast
+- bogus
 `
 			})
 
-			It("should create rules that only include that network", func() {
+			It("exits non-zero", func() {
 				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
 				Expect(err).NotTo(HaveOccurred())
 
-				Eventually(sess).Should(gexec.Exit(0))
+				Eventually(sess).Should(gexec.Exit(1))
+				Expect(sess.Err).To(gbytes.Say("allow_special_purpose: 6to4-relay-anycast is also listed in exclude_special_purpose"))
+				Expect(sess.Err).To(gbytes.Say("allow_special_purpose: unknown-special-purpose-class: bogus"))
+			})
+		})
 
-				_, err = os.Lstat(outputFile.Name())
-				Expect(err).NotTo(HaveOccurred())
+		Context("when the config excludes an unknown special-purpose class", func() {
+			BeforeEach(func() {
+				config = `
+exclude_special_purpose:
+- bogus
+`
+			})
 
-				bs, err := ioutil.ReadFile(outputFile.Name())
+			It("exits non-zero", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(bs).To(MatchJSON([]byte(`[
-					{
-							"protocol": "all",
-							"destination": "10.68.192.1-10.68.192.126"
-					},
-					{
-							"protocol": "all",
-							"destination": "10.68.192.129-10.68.192.254"
-					}
-				]`)))
+				Eventually(sess).Should(gexec.Exit(1))
+				Expect(sess.Err).To(gbytes.Say("exclude_special_purpose: unknown-special-purpose-class: bogus"))
 			})
 		})
 
-		Context("when the config contains IP ranges to exclude", func() {
+		Context("when the config allows an IP within the default excludes", func() {
 			BeforeEach(func() {
 				config = `
-include:
-- 10.68.192.0/24
-
-exclude:
-- 10.68.192.0-10.68.192.5
-- 10.68.192.127-10.68.192.128
+default_excludes:
+  allow:
+  - 169.254.169.254
 `
 			})
 
-			It("should create rules that exclude those networks", func() {
+			It("should include that IP in the public-networks ASG and report it", func() {
 				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
 				Expect(err).NotTo(HaveOccurred())
 
 				Eventually(sess).Should(gexec.Exit(0))
+				Expect(sess.Out).To(gbytes.Say("Default excludes: .*, 169.254.0.0-169.254.169.253, 169.254.169.255-169.254.255.255, .*, fe80::-febf:ffff:ffff:ffff:ffff:ffff:ffff:ffff, "))
+				Expect(sess.Out).To(gbytes.Say("Allowed within default excludes: 169.254.169.254"))
 
-				_, err = os.Lstat(outputFile.Name())
-				Expect(err).NotTo(HaveOccurred())
-
-				bs, err := ioutil.ReadFile(outputFile.Name())
+				bs, err := ioutil.ReadFile("public-networks.json")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(bs).To(MatchJSON([]byte(`
+					[
+						{
+							"protocol": "all",
+							"destination": "1.0.0.0-9.255.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "11.0.0.0-100.63.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "100.128.0.0-126.255.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "128.0.0.0-169.253.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "169.254.169.254"
+						},
+						{
+							"protocol": "all",
+							"destination": "169.255.0.0-172.15.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "172.32.0.0-192.0.1.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "192.0.3.0-192.167.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "192.169.0.0-198.17.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "198.20.0.0-198.51.99.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "198.51.101.0-203.0.112.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "203.0.114.0-223.255.255.255"
+						}
+					]`)))
+			})
+		})
+
+		Context("when the config disables the default excludes", func() {
+			BeforeEach(func() {
+				config = `
+default_excludes:
+  disabled: true
+`
+			})
+
+			It("should not exclude link-local addresses", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(sess).Should(gexec.Exit(0))
+				Expect(sess.Out).To(gbytes.Say("Default excludes: none"))
+
+				bs, err := ioutil.ReadFile("public-networks.json")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(bs).To(MatchJSON([]byte(`
+					[
+						{
+							"protocol": "all",
+							"destination": "0.0.0.0-9.255.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "11.0.0.0-172.15.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "172.32.0.0-192.167.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "192.169.0.0-255.255.255.255"
+						}
+					]`)))
+			})
+		})
+
+		Context("when the config replaces the default excludes", func() {
+			BeforeEach(func() {
+				config = `
+default_excludes:
+  ranges:
+  - 11.0.0.0/8
+`
+			})
+
+			It("should exclude the replacement ranges instead", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(sess).Should(gexec.Exit(0))
+				Expect(sess.Out).To(gbytes.Say("Default excludes: 11.0.0.0-11.255.255.255\n"))
+
+				bs, err := ioutil.ReadFile("public-networks.json")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(bs).To(MatchJSON([]byte(`
+					[
+						{
+							"protocol": "all",
+							"destination": "0.0.0.0-9.255.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "12.0.0.0-172.15.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "172.32.0.0-192.167.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "192.169.0.0-255.255.255.255"
+						}
+					]`)))
+			})
+		})
+
+		Context("when the config allows an IP outside of the default excludes", func() {
+			BeforeEach(func() {
+				config = `
+default_excludes:
+  allow:
+  - 10.0.0.1
+`
+			})
+
+			It("exits non-zero", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(sess).Should(gexec.Exit(1))
+				Expect(sess.Err).To(gbytes.Say("default_excludes: allow: 10.0.0.1 is not within the default excludes"))
+			})
+		})
+
+		Context("when the config is such that it expects a rule with a single IP", func() {
+			BeforeEach(func() {
+				config = `
+exclude:
+- 192.168.100.4
+- 192.168.100.6
+`
+			})
+
+			It("should create a rule with a single IP", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(sess).Should(gexec.Exit(0))
+
+				_, err = os.Lstat("private-networks.json")
+				Expect(err).NotTo(HaveOccurred())
+
+				bs, err := ioutil.ReadFile("private-networks.json")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(bs).To(MatchJSON([]byte(`
+					[
+						{
+							"protocol": "all",
+							"destination": "10.0.0.0-10.255.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "172.16.0.0-172.31.255.255"
+						},
+						{
+							"protocol": "all",
+							"destination": "192.168.0.0-192.168.100.3"
+						},
+						{
+							"protocol": "all",
+							"destination": "192.168.100.5"
+						},
+						{
+							"protocol": "all",
+							"destination": "192.168.100.7-192.168.255.255"
+						}
+					]`)))
+			})
+		})
+	})
+
+	Context("when given a config and an output file", func() {
+		var configFile *os.File
+		var outputFile *os.File
+		var config string
+
+		JustBeforeEach(func() {
+			var err error
+			configFile, err = ioutil.TempFile("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ioutil.WriteFile(configFile.Name(), []byte(config), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			outputFile, err = ioutil.TempFile("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			cmd = exec.Command(binPath, "create", "--config", configFile.Name(), "--output", outputFile.Name())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(configFile.Name())
+			os.RemoveAll(outputFile.Name())
+		})
+
+		Context("when the config contains networks to include", func() {
+			BeforeEach(func() {
+				config = `
+include:
+- 10.68.192.0/24
+
+exclude:
+- 10.68.192.0
+- 10.68.192.127
+- 10.68.192.128
+- 10.68.192.255
+`
+			})
+
+			It("should create rules that only include that network", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(sess).Should(gexec.Exit(0))
+
+				_, err = os.Lstat(outputFile.Name())
+				Expect(err).NotTo(HaveOccurred())
+
+				bs, err := ioutil.ReadFile(outputFile.Name())
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(bs).To(MatchJSON([]byte(`[
+					{
+							"protocol": "all",
+							"destination": "10.68.192.1-10.68.192.126"
+					},
+					{
+							"protocol": "all",
+							"destination": "10.68.192.129-10.68.192.254"
+					}
+				]`)))
+			})
+		})
+
+		Context("when the config contains IPv4 and IPv6 networks to include", func() {
+			BeforeEach(func() {
+				config = `
+include:
+- 10.68.192.0/24
+- 2001:db8::/64
+
+exclude:
+- 10.68.192.0-10.68.192.127
+- 2001:db8::100-2001:db8::1ff
+`
+			})
+
+			It("should slice each address family independently", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(sess).Should(gexec.Exit(0))
+
+				bs, err := ioutil.ReadFile(outputFile.Name())
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(bs).To(MatchJSON([]byte(`[
+					{
+							"protocol": "all",
+							"destination": "10.68.192.128-10.68.192.255"
+					},
+					{
+							"protocol": "all",
+							"destination": "2001:db8::-2001:db8::ff"
+					},
+					{
+							"protocol": "all",
+							"destination": "2001:db8::200-2001:db8::ffff:ffff:ffff:ffff"
+					}
+				]`)))
+			})
+		})
+
+		Context("when the config includes non-routable special-purpose networks", func() {
+			BeforeEach(func() {
+				config = `
+include:
+- 100.64.0.0/10
+- 169.254.169.254
+- 192.0.2.0/24
+`
+			})
+
+			It("should only exclude link-local addresses from them", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(sess).Should(gexec.Exit(0))
+				Expect(sess.Out).To(gbytes.Say(`Default excludes: 169\.254\.0\.0-169\.254\.255\.255, fe80::-febf:ffff:ffff:ffff:ffff:ffff:ffff:ffff\n`))
+
+				bs, err := ioutil.ReadFile(outputFile.Name())
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(bs).To(MatchJSON([]byte(`[
+					{
+							"protocol": "all",
+							"destination": "100.64.0.0-100.127.255.255"
+					},
+					{
+							"protocol": "all",
+							"destination": "192.0.2.0-192.0.2.255"
+					}
+				]`)))
+			})
+		})
+
+		Context("when every included address is excluded", func() {
+			BeforeEach(func() {
+				config = `
+include:
+- 169.254.0.0/16
+- 10.0.0.0/24
+exclude:
+- 10.0.0.0/24
+`
+			})
+
+			It("exits non-zero without writing the rules", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(sess).Should(gexec.Exit(1))
+				Expect(sess.Err).To(gbytes.Say("security group .* has no rules: every included address is excluded"))
+
+				bs, err := ioutil.ReadFile(outputFile.Name())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(bs).To(BeEmpty())
+			})
+		})
+
+		Context("when the config contains protocols", func() {
+			BeforeEach(func() {
+				config = `
+include:
+- 10.68.192.0/24
+
+exclude:
+- 10.68.192.128-10.68.192.255
+
+protocols:
+- protocol: tcp
+  ports: 443,80
+  log: true
+- protocol: udp
+  ports: 53
+- protocol: icmp
+  type: 0
+  code: -1
+`
+			})
+
+			It("should create a rule for each protocol and destination", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(sess).Should(gexec.Exit(0))
+
+				bs, err := ioutil.ReadFile(outputFile.Name())
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(bs).To(MatchJSON([]byte(`[
+					{
+							"protocol": "tcp",
+							"destination": "10.68.192.0-10.68.192.127",
+							"ports": "443,80",
+							"log": true
+					},
+					{
+							"protocol": "udp",
+							"destination": "10.68.192.0-10.68.192.127",
+							"ports": "53"
+					},
+					{
+							"protocol": "icmp",
+							"destination": "10.68.192.0-10.68.192.127",
+							"type": 0,
+							"code": -1
+					}
+				]`)))
+			})
+		})
+
+		Context("when the config contains IP ranges to exclude", func() {
+			BeforeEach(func() {
+				config = `
+include:
+- 10.68.192.0/24
+
+exclude:
+- 10.68.192.0-10.68.192.5
+- 10.68.192.127-10.68.192.128
+`
+			})
+
+			It("should create rules that exclude those networks", func() {
+				sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(sess).Should(gexec.Exit(0))
+
+				_, err = os.Lstat(outputFile.Name())
+				Expect(err).NotTo(HaveOccurred())
+
+				bs, err := ioutil.ReadFile(outputFile.Name())
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(bs).To(MatchJSON([]byte(`[
@@ -500,4 +1136,55 @@ exclude:
 			})
 		})
 	})
+
+	Context("when given an include config and the cidr format", func() {
+		var configFile *os.File
+		var outputFile *os.File
+
+		BeforeEach(func() {
+			var err error
+			configFile, err = ioutil.TempFile("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ioutil.WriteFile(configFile.Name(), []byte(`
+include:
+- 10.68.192.0/24
+
+exclude:
+- 10.68.192.0
+- 10.68.192.128-10.68.192.255
+`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			outputFile, err = ioutil.TempFile("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			cmd = exec.Command(binPath, "create", "--config", configFile.Name(), "--output", outputFile.Name(), "--format", "cidr")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(configFile.Name())
+			os.RemoveAll(outputFile.Name())
+		})
+
+		It("writes one rule per CIDR block", func() {
+			sess, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(sess).Should(gexec.Exit(0))
+
+			bs, err := ioutil.ReadFile(outputFile.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(bs).To(MatchJSON([]byte(`[
+				{"protocol": "all", "destination": "10.68.192.1/32"},
+				{"protocol": "all", "destination": "10.68.192.2/31"},
+				{"protocol": "all", "destination": "10.68.192.4/30"},
+				{"protocol": "all", "destination": "10.68.192.8/29"},
+				{"protocol": "all", "destination": "10.68.192.16/28"},
+				{"protocol": "all", "destination": "10.68.192.32/27"},
+				{"protocol": "all", "destination": "10.68.192.64/26"}
+			]`)))
+		})
+	})
 })
@@ -0,0 +1,89 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diff", func() {
+	var (
+		oldFile *os.File
+		newFile *os.File
+		args    []string
+		sess    *gexec.Session
+	)
+
+	writeTempFile := func(contents string) *os.File {
+		file, err := ioutil.TempFile("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(file.Name(), []byte(contents), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		return file
+	}
+
+	BeforeEach(func() {
+		oldFile = writeTempFile(`[
+			{"protocol": "all", "destination": "11.0.0.0-11.0.0.255"},
+			{"protocol": "tcp", "destination": "10.0.0.0/24", "ports": "443"}
+		]`)
+
+		newFile = writeTempFile(`[
+			{"protocol": "all", "destination": "11.0.0.0-11.0.0.4"},
+			{"protocol": "all", "destination": "11.0.0.6-11.0.0.255"},
+			{"protocol": "tcp", "destination": "10.0.0.0/23", "ports": "443"}
+		]`)
+
+		args = nil
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		sess, err = gexec.Start(exec.Command(binPath, append(append([]string{"diff"}, args...), oldFile.Name(), newFile.Name())...), GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(oldFile.Name())
+		os.RemoveAll(newFile.Name())
+	})
+
+	It("prints a summary of the changes", func() {
+		Eventually(sess).Should(gexec.Exit(0))
+		Expect(sess.Out).To(gbytes.Say("all: 0 addresses newly allowed, 1 address newly denied"))
+		Expect(sess.Out).To(gbytes.Say(`- 11.0.0.5\n`))
+		Expect(sess.Out).To(gbytes.Say("tcp 443: 256 addresses newly allowed, 0 addresses newly denied"))
+		Expect(sess.Out).To(gbytes.Say(`\+ 10.0.1.0-10.0.1.255\n`))
+	})
+
+	Context("when given --json", func() {
+		BeforeEach(func() {
+			args = []string{"--json"}
+		})
+
+		It("prints the changes as JSON", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out.Contents()).To(MatchJSON(`[
+				{
+					"protocol": "all",
+					"allowed": [],
+					"denied": ["11.0.0.5"]
+				},
+				{
+					"protocol": "tcp",
+					"ports": "443",
+					"allowed": ["10.0.1.0-10.0.1.255"],
+					"denied": []
+				}
+			]`))
+		})
+	})
+})
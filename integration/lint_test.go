@@ -0,0 +1,185 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lint", func() {
+	var (
+		dir    string
+		policy string
+		rules  string
+		sess   *gexec.Session
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		policy = `
+max_rules: 3
+forbidden_destinations:
+- 169.254.169.254
+forbidden_rules:
+- protocol: all
+  destination: 0.0.0.0/0
+require_log:
+- 10.0.0.0/8
+`
+	})
+
+	JustBeforeEach(func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "policy.yml"), []byte(policy), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "rules.json"), []byte(rules), 0644)).To(Succeed())
+
+		var err error
+		cmd := exec.Command(binPath, "lint", "--policy", filepath.Join(dir, "policy.yml"), filepath.Join(dir, "rules.json"))
+		sess, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Context("when the rules comply with the policy", func() {
+		BeforeEach(func() {
+			rules = `[
+				{"protocol": "tcp", "destination": "10.0.0.0-10.0.0.255", "ports": "443", "log": true},
+				{"protocol": "udp", "destination": "10.0.1.0-10.0.1.255", "ports": "53"},
+				{"protocol": "all", "destination": "11.0.0.0-169.254.169.253"}
+			]`
+		})
+
+		It("exits zero", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say("OK"))
+		})
+	})
+
+	Context("when the rules violate the policy", func() {
+		BeforeEach(func() {
+			rules = `[
+				{"protocol": "all", "destination": "0.0.0.0-255.255.255.255"},
+				{"protocol": "tcp", "destination": "10.0.0.0-10.0.0.255", "ports": "443"},
+				{"protocol": "tcp", "destination": "0.0.0.0-255.255.255.255", "ports": "443", "log": true},
+				{"protocol": "all", "destination": "192.168.0.1"}
+			]`
+		})
+
+		It("reports each violation and exits non-zero", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+
+			file := filepath.Join(dir, "rules.json")
+			Expect(sess.Out).To(gbytes.Say(file + ": 4 rules exceed the maximum of 3\n"))
+			Expect(sess.Out).To(gbytes.Say(file + ": rule 1: destination '0.0.0.0-255.255.255.255' reaches forbidden destination 169.254.169.254\n"))
+			Expect(sess.Out).To(gbytes.Say(file + ": rule 1: destination '0.0.0.0-255.255.255.255' allows all of 0.0.0.0-255.255.255.255 with all\n"))
+			Expect(sess.Out).To(gbytes.Say(file + ": rule 1: destination '0.0.0.0-255.255.255.255' reaches 10.0.0.0-10.255.255.255 without log\n"))
+			Expect(sess.Out).To(gbytes.Say(file + ": rule 2: destination '10.0.0.0-10.0.0.255' reaches 10.0.0.0-10.255.255.255 without log\n"))
+			Expect(sess.Out).To(gbytes.Say(file + ": rule 3: destination '0.0.0.0-255.255.255.255' reaches forbidden destination 169.254.169.254\n"))
+			Expect(sess.Out).NotTo(gbytes.Say("rule 4"))
+			Expect(sess.Err).To(gbytes.Say("6 policy violation\\(s\\)"))
+		})
+	})
+
+	Context("when a rule for all protocols covers a destination forbidden for one protocol", func() {
+		BeforeEach(func() {
+			policy = `
+forbidden_rules:
+- protocol: tcp
+  destination: 0.0.0.0/0
+`
+			rules = `[
+				{"protocol": "all", "destination": "0.0.0.0-255.255.255.255"},
+				{"protocol": "udp", "destination": "0.0.0.0-255.255.255.255"}
+			]`
+		})
+
+		It("reports the violation", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+
+			file := filepath.Join(dir, "rules.json")
+			Expect(sess.Out).To(gbytes.Say(file + ": rule 1: destination '0.0.0.0-255.255.255.255' allows all of 0.0.0.0-255.255.255.255 with tcp\n"))
+			Expect(sess.Out).NotTo(gbytes.Say("rule 2"))
+			Expect(sess.Err).To(gbytes.Say("1 policy violation\\(s\\)"))
+		})
+	})
+
+	Context("when several rules together cover a forbidden destination", func() {
+		BeforeEach(func() {
+			policy = `
+forbidden_rules:
+- protocol: all
+  destination: 0.0.0.0/0
+`
+			rules = `[
+				{"protocol": "all", "destination": "0.0.0.0-127.255.255.255"},
+				{"protocol": "tcp", "destination": "10.0.0.0/8", "ports": "443"},
+				{"protocol": "all", "destination": "128.0.0.0-255.255.255.255"}
+			]`
+		})
+
+		It("reports the rules together", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+
+			file := filepath.Join(dir, "rules.json")
+			Expect(sess.Out).To(gbytes.Say(file + ": rules 1 and 3: destinations together allow all of 0.0.0.0-255.255.255.255 with all\n"))
+			Expect(sess.Err).To(gbytes.Say("1 policy violation\\(s\\)"))
+		})
+	})
+
+	Context("when rules for different protocols together cover a destination forbidden for any protocol", func() {
+		BeforeEach(func() {
+			policy = `
+forbidden_rules:
+- destination: 0.0.0.0/0
+`
+			rules = `[
+				{"protocol": "tcp", "destination": "0.0.0.0-127.255.255.255", "ports": "443"},
+				{"protocol": "udp", "destination": "128.0.0.0-255.255.255.255", "ports": "53"}
+			]`
+		})
+
+		It("does not report them, as no single protocol reaches all of it", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say("OK"))
+		})
+	})
+
+	Context("when a destination range ends before it starts", func() {
+		BeforeEach(func() {
+			rules = `[{"protocol": "all", "destination": "10.0.0.9-10.0.0.1"}]`
+		})
+
+		It("reports the invalid destination", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Out).To(gbytes.Say("rule 1: invalid destination '10.0.0.9-10.0.0.1': invalid-range-start-after-end: 10.0.0.9-10.0.0.1\n"))
+			Expect(sess.Out).NotTo(gbytes.Say("forbidden"))
+		})
+	})
+
+	Context("when the policy contains an invalid range", func() {
+		BeforeEach(func() {
+			policy = `
+forbidden_destinations:
+- 10.0.0.9-10.0.0.1
+`
+			rules = `[]`
+		})
+
+		It("reports the problem with its line number", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("line 3: forbidden_destinations: invalid-range-start-after-end: 10.0.0.9-10.0.0.1"))
+		})
+	})
+})
@@ -0,0 +1,166 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Create with --max-rules", func() {
+	var (
+		tempDir string
+		config  string
+		args    []string
+		sess    *gexec.Session
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		config = "exclude: []\n"
+		args = nil
+	})
+
+	JustBeforeEach(func() {
+		configPath := filepath.Join(tempDir, "config.yml")
+		err := ioutil.WriteFile(configPath, []byte(config), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		cmd := exec.Command(binPath, append([]string{"create", "--config", configPath, "--output-dir", tempDir}, args...)...)
+		sess, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	Context("when a security group has more rules than the maximum", func() {
+		BeforeEach(func() {
+			config = `
+security_groups:
+- name: services
+  include:
+  - 10.0.0.0/24
+  exclude:
+  - 10.0.0.10
+  - 10.0.0.20
+  - 10.0.0.30
+  bind:
+    running: true
+    spaces:
+    - org: my-org
+      space: my-space
+      lifecycle: staging
+- name: small
+  include:
+  - 10.1.0.0/24
+`
+			args = []string{"--max-rules", "3"}
+		})
+
+		It("splits it into numbered groups covering the same ranges", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+
+			first, err := ioutil.ReadFile(filepath.Join(tempDir, "services-1.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first).To(MatchJSON(`[
+				{"protocol": "all", "destination": "10.0.0.0-10.0.0.9"},
+				{"protocol": "all", "destination": "10.0.0.11-10.0.0.19"},
+				{"protocol": "all", "destination": "10.0.0.21-10.0.0.29"}
+			]`))
+
+			second, err := ioutil.ReadFile(filepath.Join(tempDir, "services-2.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second).To(MatchJSON(`[
+				{"protocol": "all", "destination": "10.0.0.31-10.0.0.255"}
+			]`))
+
+			Expect(filepath.Join(tempDir, "services.json")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(tempDir, "small.json")).To(BeAnExistingFile())
+		})
+
+		It("prints the commands to create and bind each group", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say("Create and bind the security groups with:"))
+			Expect(sess.Out).To(gbytes.Say(`cf create-security-group services-1 .*services-1.json\n`))
+			Expect(sess.Out).To(gbytes.Say(`cf bind-running-security-group services-1\n`))
+			Expect(sess.Out).To(gbytes.Say(`cf bind-security-group services-1 my-org --space my-space --lifecycle staging\n`))
+			Expect(sess.Out).To(gbytes.Say(`cf create-security-group services-2 .*services-2.json\n`))
+			Expect(sess.Out).To(gbytes.Say(`cf bind-running-security-group services-2\n`))
+			Expect(sess.Out).To(gbytes.Say(`cf bind-security-group services-2 my-org --space my-space --lifecycle staging\n`))
+			Expect(sess.Out).To(gbytes.Say(`cf create-security-group small .*small.json\n`))
+			Expect(sess.Out).To(gbytes.Say("OK"))
+		})
+	})
+
+	Context("when creating the default security groups", func() {
+		BeforeEach(func() {
+			args = []string{"--max-rules", "2"}
+		})
+
+		It("binds the numbered groups for running and staging", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			first, err := ioutil.ReadFile(filepath.Join(tempDir, "private-networks-1.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first).To(MatchJSON(`[
+				{"protocol": "all", "destination": "10.0.0.0-10.255.255.255"},
+				{"protocol": "all", "destination": "172.16.0.0-172.31.255.255"}
+			]`))
+
+			second, err := ioutil.ReadFile(filepath.Join(tempDir, "private-networks-2.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second).To(MatchJSON(`[
+				{"protocol": "all", "destination": "192.168.0.0-192.168.255.255"}
+			]`))
+
+			Expect(filepath.Join(tempDir, "private-networks.json")).NotTo(BeAnExistingFile())
+
+			Expect(sess.Out).To(gbytes.Say(`cf create-security-group private-networks-1 .*private-networks-1.json\n`))
+			Expect(sess.Out).To(gbytes.Say(`cf bind-running-security-group private-networks-1\n`))
+			Expect(sess.Out).To(gbytes.Say(`cf bind-staging-security-group private-networks-1\n`))
+			Expect(sess.Out).To(gbytes.Say(`cf create-security-group private-networks-2 .*private-networks-2.json\n`))
+			Expect(sess.Out).To(gbytes.Say(`cf bind-running-security-group private-networks-2\n`))
+			Expect(sess.Out).To(gbytes.Say(`cf bind-staging-security-group private-networks-2\n`))
+		})
+	})
+
+	Context("when --max-rules is negative", func() {
+		BeforeEach(func() {
+			args = []string{"--max-rules", "-1"}
+		})
+
+		It("exits with an error", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("--max-rules must not be negative"))
+		})
+	})
+
+	for _, format := range []string{"bosh", "terraform"} {
+		format := format
+
+		Context("when given --format "+format, func() {
+			BeforeEach(func() {
+				args = []string{"--max-rules", "2", "--format", format}
+			})
+
+			It("exits with an error without writing anything", func() {
+				Eventually(sess).Should(gexec.Exit(1))
+				Expect(sess.Err).To(gbytes.Say("--max-rules cannot be used with --format " + format))
+
+				entries, err := ioutil.ReadDir(tempDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+			})
+		})
+	}
+})
@@ -0,0 +1,97 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Optimize", func() {
+	var (
+		tempDir   string
+		rulesPath string
+		args      []string
+		sess      *gexec.Session
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		rulesPath = filepath.Join(tempDir, "rules.json")
+		err = ioutil.WriteFile(rulesPath, []byte(`[
+			{"protocol": "tcp", "destination": "10.0.0.0/25", "ports": "443"},
+			{"protocol": "tcp", "destination": "10.0.0.128-10.0.0.255", "ports": "443"},
+			{"protocol": "tcp", "destination": "10.0.0.100", "ports": "443"},
+			{"protocol": "tcp", "destination": "10.0.0.0/24", "ports": "443", "log": true},
+			{"protocol": "all", "destination": "11.0.0.0-11.0.0.9"}
+		]`), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		args = nil
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		sess, err = gexec.Start(exec.Command(binPath, append(append([]string{"optimize"}, args...), rulesPath)...), GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("writes the optimized rules to stdout and the report to stderr", func() {
+		Eventually(sess).Should(gexec.Exit(0))
+		Expect(sess.Out.Contents()).To(MatchJSON(`[
+			{"protocol": "tcp", "destination": "10.0.0.0-10.0.0.255", "ports": "443"},
+			{"protocol": "tcp", "destination": "10.0.0.0-10.0.0.255", "ports": "443", "log": true},
+			{"protocol": "all", "destination": "11.0.0.0-11.0.0.9"}
+		]`))
+
+		Expect(sess.Err).To(gbytes.Say(`tcp 443: 3 rules -> 1 rule, 256 addresses\n`))
+		Expect(sess.Err).To(gbytes.Say(`tcp 443 \(log\): 1 rule -> 1 rule, 256 addresses\n`))
+		Expect(sess.Err).To(gbytes.Say(`all: 1 rule -> 1 rule, 10 addresses\n`))
+		Expect(sess.Err).To(gbytes.Say(`Optimized 5 rules to 3 rules\n`))
+		Expect(sess.Err).To(gbytes.Say(`Allowed address space unchanged\n`))
+	})
+
+	Context("when given an output file", func() {
+		var outputPath string
+
+		BeforeEach(func() {
+			outputPath = filepath.Join(tempDir, "optimized.json")
+			args = []string{"-o", outputPath}
+		})
+
+		It("writes the optimized rules to the file", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say(`Optimized 5 rules to 3 rules\n`))
+			Expect(sess.Out).To(gbytes.Say(`Wrote ` + outputPath))
+
+			bs, err := ioutil.ReadFile(outputPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bs).To(ContainSubstring(`"destination": "10.0.0.0-10.0.0.255"`))
+		})
+	})
+
+	Context("when a destination is invalid", func() {
+		BeforeEach(func() {
+			err := ioutil.WriteFile(rulesPath, []byte(`[{"protocol": "all", "destination": "nope"}]`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("exits with an error", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("invalid destination 'nope'"))
+		})
+	})
+})
@@ -0,0 +1,241 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Create output", func() {
+	var (
+		configFile *os.File
+		config     string
+		args       []string
+		sess       *gexec.Session
+	)
+
+	BeforeEach(func() {
+		config = "exclude: []\n"
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		configFile, err = ioutil.TempFile("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(configFile.Name(), []byte(config), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		cmd := exec.Command(binPath, append([]string{"create", "--config", configFile.Name()}, args...)...)
+		sess, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(configFile.Name())
+	})
+
+	Context("when the output is - and the config contains include", func() {
+		BeforeEach(func() {
+			config = `
+include:
+- 10.68.192.0/24
+`
+			args = []string{"--output", "-"}
+		})
+
+		It("writes only the rules to stdout", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out.Contents()).To(MatchJSON(`[
+				{"protocol": "all", "destination": "10.68.192.0-10.68.192.255"}
+			]`))
+			Expect(sess.Err).To(gbytes.Say("OK"))
+		})
+	})
+
+	Context("when the output is - and the config creates several security groups", func() {
+		BeforeEach(func() {
+			args = []string{"--output", "-"}
+		})
+
+		It("writes the rules of every security group to stdout, keyed by name", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+
+			var rulesByName map[string][]map[string]interface{}
+			Expect(json.Unmarshal(sess.Out.Contents(), &rulesByName)).To(Succeed())
+			Expect(rulesByName).To(HaveKey("public-networks"))
+			Expect(rulesByName).To(HaveKey("private-networks"))
+			Expect(rulesByName).To(HaveKey("public-networks-ipv6"))
+			Expect(rulesByName).To(HaveKey("private-networks-ipv6"))
+			Expect(rulesByName["private-networks"]).To(HaveLen(3))
+		})
+	})
+
+	Context("when given an output directory", func() {
+		var outputDir string
+
+		BeforeEach(func() {
+			var err error
+			outputDir, err = ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			args = []string{"--output-dir", filepath.Join(outputDir, "asgs")}
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(outputDir)
+		})
+
+		It("writes the default security groups into the directory", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+
+			for _, name := range []string{"public-networks", "private-networks", "public-networks-ipv6", "private-networks-ipv6"} {
+				Expect(sess.Out).To(gbytes.Say("Wrote " + filepath.Join(outputDir, "asgs", name+".json")))
+				_, err := os.Stat(filepath.Join(outputDir, "asgs", name+".json"))
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			_, err := os.Stat("public-networks.json")
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("when the format is bosh", func() {
+		BeforeEach(func() {
+			config = `
+security_groups:
+- name: public-networks
+  include:
+  - 11.0.0.0-11.0.0.9
+  exclude:
+  - 11.0.0.5
+- name: db
+  include:
+  - 10.0.1.0/24
+  protocols:
+  - protocol: tcp
+    ports: 5432
+`
+		})
+
+		Context("when the output is -", func() {
+			BeforeEach(func() {
+				args = []string{"--format", "bosh", "--output", "-"}
+			})
+
+			It("writes an ops file replacing the security group definitions to stdout", func() {
+				Eventually(sess).Should(gexec.Exit(0))
+				Expect(sess.Out.Contents()).To(MatchYAML(`
+- type: replace
+  path: /instance_groups/name=api/jobs/name=cloud_controller_ng/properties/cc/security_group_definitions/name=public_networks?/rules
+  value:
+  - protocol: all
+    destination: 11.0.0.0-11.0.0.4
+  - protocol: all
+    destination: 11.0.0.6-11.0.0.9
+- type: replace
+  path: /instance_groups/name=api/jobs/name=cloud_controller_ng/properties/cc/security_group_definitions/name=db?/rules
+  value:
+  - protocol: tcp
+    destination: 10.0.1.0-10.0.1.255
+    ports: "5432"
+`))
+				Expect(sess.Err).To(gbytes.Say("OK"))
+			})
+		})
+
+		Context("when given an output directory", func() {
+			var outputDir string
+
+			BeforeEach(func() {
+				var err error
+				outputDir, err = ioutil.TempDir("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				args = []string{"--format", "bosh", "--output-dir", outputDir}
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(outputDir)
+			})
+
+			It("writes a single ops file into the directory", func() {
+				Eventually(sess).Should(gexec.Exit(0))
+				Expect(sess.Out).To(gbytes.Say("Wrote " + filepath.Join(outputDir, "security-groups-ops.yml")))
+
+				bs, err := ioutil.ReadFile(filepath.Join(outputDir, "security-groups-ops.yml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(bs)).To(ContainSubstring("security_group_definitions/name=db?/rules"))
+			})
+		})
+	})
+
+	Context("when the format is terraform and the output is -", func() {
+		BeforeEach(func() {
+			config = `
+security_groups:
+- name: public-networks
+  include:
+  - 11.0.0.0-11.0.0.9
+  exclude:
+  - 11.0.0.5
+- name: db
+  include:
+  - 10.0.1.0/24
+  protocols:
+  - protocol: tcp
+    ports: 5432
+    log: true
+  - protocol: icmp
+    type: 0
+    code: -1
+`
+			args = []string{"--format", "terraform", "--output", "-"}
+		})
+
+		It("writes cloudfoundry_asg resources to stdout", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(string(sess.Out.Contents())).To(Equal(`resource "cloudfoundry_asg" "public_networks" {
+  name = "public-networks"
+
+  rule {
+    protocol    = "all"
+    destination = "11.0.0.0-11.0.0.4"
+  }
+
+  rule {
+    protocol    = "all"
+    destination = "11.0.0.6-11.0.0.9"
+  }
+}
+
+resource "cloudfoundry_asg" "db" {
+  name = "db"
+
+  rule {
+    protocol    = "tcp"
+    destination = "10.0.1.0-10.0.1.255"
+    ports       = "5432"
+    log         = true
+  }
+
+  rule {
+    protocol    = "icmp"
+    destination = "10.0.1.0-10.0.1.255"
+    type        = 0
+    code        = -1
+  }
+}
+`))
+			Expect(sess.Err).To(gbytes.Say("OK"))
+		})
+	})
+})
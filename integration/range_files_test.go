@@ -0,0 +1,230 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Create with range files", func() {
+	var (
+		dir    string
+		config string
+		sess   *gexec.Session
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(dir, "blacklists"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "networks.txt"), []byte("10.0.0.0/24\n"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "blacklists", "bosh.txt"), []byte("# director\n10.0.0.6\n\n10.0.0.10-10.0.0.19 # service VMs\n"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "blacklists", "other.json"), []byte(`["10.0.0.200/29"]`), 0644)).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		configPath := filepath.Join(dir, "config.yml")
+		Expect(ioutil.WriteFile(configPath, []byte(config), 0644)).To(Succeed())
+
+		var err error
+		cmd := exec.Command(binPath, "create", "--config", configPath, "--output", filepath.Join(dir, "out.json"))
+		sess, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Context("when the config lists files relative to itself", func() {
+		BeforeEach(func() {
+			config = `
+include_files:
+- networks.txt
+exclude:
+- 10.0.0.0-10.0.0.4
+exclude_files:
+- blacklists
+`
+		})
+
+		It("merges their contents into the includes and excludes", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+
+			bs, err := ioutil.ReadFile(filepath.Join(dir, "out.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bs).To(MatchJSON(`[
+				{"protocol": "all", "destination": "10.0.0.5"},
+				{"protocol": "all", "destination": "10.0.0.7-10.0.0.9"},
+				{"protocol": "all", "destination": "10.0.0.20-10.0.0.199"},
+				{"protocol": "all", "destination": "10.0.0.208-10.0.0.255"}
+			]`))
+		})
+	})
+
+	Context("when a file contains invalid entries", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "blacklists", "bad.txt"), []byte("10.0.0.1\n10.0.0.9-10.0.0.1\n"), 0644)).To(Succeed())
+
+			config = `
+include:
+- 10.0.0.0/24
+exclude_files:
+- blacklists/*.txt
+- missing/*.txt
+`
+		})
+
+		It("reports each problem and writes nothing", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("exclude_files: " + filepath.Join(dir, "blacklists", "bad.txt") + ": line 2: invalid-range-start-after-end: 10.0.0.9-10.0.0.1"))
+			Expect(sess.Err).To(gbytes.Say("exclude_files: path '" + filepath.Join(dir, "missing", "\\*.txt") + "' does not exist"))
+			Expect(sess.Err).To(gbytes.Say("config has 2 problem"))
+
+			_, err := os.Stat(filepath.Join(dir, "out.json"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("when the config excludes BOSH VMs and networks", func() {
+		var director *ghttp.Server
+
+		BeforeEach(func() {
+			director = ghttp.NewServer()
+			director.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/info"),
+					ghttp.RespondWith(http.StatusOK, `{"user_authentication": {"type": "basic"}}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/deployments/cf/vms"),
+					ghttp.VerifyBasicAuth("admin", "secret"),
+					ghttp.RespondWith(http.StatusOK, `[{"job": "router", "ips": ["10.0.0.50"]}, {"job": "api", "ips": ["10.0.0.60"]}]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/deployments/redis/vms"),
+					ghttp.RespondWith(http.StatusOK, `[]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/configs", "type=cloud&latest=true"),
+					ghttp.RespondWith(http.StatusOK, `[{"content": "networks:\n- name: services\n  subnets:\n  - range: 10.0.0.240/28\n"}]`),
+				),
+			)
+
+			Expect(ioutil.WriteFile(filepath.Join(dir, "vms.json"), []byte(`{"Tables": [{"Title": "Deployment 'redis'", "Rows": [{"instance": "redis/1", "ips": "10.0.0.100"}]}]}`), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "cloud-config.yml"), []byte(`
+networks:
+- name: default
+  subnets:
+  - range: 10.0.0.0/25
+- name: services
+  subnets:
+  - range: 10.0.0.192/26
+`), 0644)).To(Succeed())
+
+			config = `
+include:
+- 10.0.0.0/24
+exclude_bosh:
+  director:
+    url: ` + director.URL() + `
+    client: admin
+    client_secret: secret
+  vms_files:
+  - vms.json
+  cloud_config_files:
+  - cloud-config.yml
+  deployments:
+  - cf
+  - redis
+  instance_groups:
+  - router
+  - redis
+  networks:
+  - services
+`
+		})
+
+		AfterEach(func() {
+			director.Close()
+		})
+
+		It("excludes the VM IPs and network ranges", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+
+			bs, err := ioutil.ReadFile(filepath.Join(dir, "out.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bs).To(MatchJSON(`[
+				{"protocol": "all", "destination": "10.0.0.0-10.0.0.49"},
+				{"protocol": "all", "destination": "10.0.0.51-10.0.0.99"},
+				{"protocol": "all", "destination": "10.0.0.101-10.0.0.191"}
+			]`))
+		})
+	})
+
+	Context("when the config includes cloud provider ranges", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "ip-ranges.json"), []byte(`{
+				"prefixes": [
+					{"ip_prefix": "52.216.0.0/15", "region": "us-east-1", "service": "S3"},
+					{"ip_prefix": "52.218.0.0/17", "region": "us-west-2", "service": "S3"},
+					{"ip_prefix": "52.94.0.0/22", "region": "us-east-1", "service": "AMAZON"}
+				],
+				"ipv6_prefixes": []
+			}`), 0644)).To(Succeed())
+
+			config = `
+include_cloud_ranges:
+- provider: aws
+  file: ip-ranges.json
+  services:
+  - S3
+exclude_cloud_ranges:
+- provider: aws
+  file: ip-ranges.json
+  services:
+  - S3
+  regions:
+  - us-west-2
+`
+		})
+
+		It("uses the selected prefixes as includes and excludes", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+
+			bs, err := ioutil.ReadFile(filepath.Join(dir, "out.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bs).To(MatchJSON(`[
+				{"protocol": "all", "destination": "52.216.0.0-52.217.255.255"}
+			]`))
+		})
+	})
+
+	Context("when a cloud provider entry is invalid", func() {
+		BeforeEach(func() {
+			config = `
+include:
+- 10.0.0.0/24
+exclude_cloud_ranges:
+- provider: oracle
+  file: ranges.json
+`
+		})
+
+		It("reports the problem", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("exclude_cloud_ranges: entry 1: provider must be one of aws, gcp, azure"))
+		})
+	})
+})
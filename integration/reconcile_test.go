@@ -0,0 +1,236 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reconcile", func() {
+	var (
+		server     *ghttp.Server
+		configFile *os.File
+		configYAML string
+		args       []string
+		sess       *gexec.Session
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		args = nil
+		configYAML = `
+security_groups:
+- name: db
+  include:
+  - 10.0.1.0/24
+  protocols:
+  - protocol: tcp
+    ports: 5432
+  bind:
+    running: true
+`
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		configFile, err = ioutil.TempFile("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(configFile.Name(), []byte(configYAML), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		cmd := exec.Command(binPath, append([]string{"reconcile", "--config", configFile.Name()}, args...)...)
+		cmd.Env = append(os.Environ(), "CF_API="+server.URL(), "CF_TOKEN=some-token")
+		sess, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(configFile.Name())
+	})
+
+	Context("when the live security group matches the config", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v3/security_groups", "names=db"),
+				ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": [{
+					"guid": "db-guid",
+					"name": "db",
+					"rules": [
+						{"protocol": "tcp", "destination": "10.0.1.0/25", "ports": "5432"},
+						{"protocol": "tcp", "destination": "10.0.1.128-10.0.1.255", "ports": "5432", "description": "added by hand"}
+					],
+					"globally_enabled": {"running": true, "staging": false}
+				}]}`),
+			))
+		})
+
+		It("reports that it is in sync", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say("db: in sync"))
+			Expect(sess.Out).To(gbytes.Say("OK"))
+		})
+	})
+
+	Context("when the live security group has drifted", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v3/security_groups", "names=db"),
+				ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": [{
+					"guid": "db-guid",
+					"name": "db",
+					"rules": [
+						{"protocol": "tcp", "destination": "10.0.1.0-10.0.1.127", "ports": "5432"},
+						{"protocol": "udp", "destination": "10.0.2.1", "ports": "53"}
+					],
+					"globally_enabled": {"running": true, "staging": true}
+				}]}`),
+			))
+		})
+
+		It("reports the drift and exits non-zero", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Out).To(gbytes.Say(`db: tcp 5432: 128 addresses missing, 0 addresses unexpected\n\+ 10.0.1.128-10.0.1.255\n`))
+			Expect(sess.Out).To(gbytes.Say(`db: udp 53: 0 addresses missing, 1 address unexpected\n- 10.0.2.1\n`))
+			Expect(sess.Out).To(gbytes.Say("db: globally enabled for staging is true, expected false"))
+			Expect(sess.Err).To(gbytes.Say("1 security group\\(s\\) have drifted"))
+		})
+
+		Context("with --fix", func() {
+			BeforeEach(func() {
+				args = []string{"--fix"}
+
+				server.AppendHandlers(ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PATCH", "/v3/security_groups/db-guid"),
+					ghttp.VerifyJSON(`{
+						"name": "db",
+						"rules": [{"protocol": "tcp", "destination": "10.0.1.0-10.0.1.255", "ports": "5432"}],
+						"globally_enabled": {"running": true, "staging": false}
+					}`),
+					ghttp.RespondWith(http.StatusOK, `{"guid": "db-guid", "name": "db"}`),
+				))
+			})
+
+			It("updates the security group to match the config", func() {
+				Eventually(sess).Should(gexec.Exit(0))
+				Expect(sess.Out).To(gbytes.Say("Updated security group db"))
+				Expect(sess.Out).To(gbytes.Say("OK"))
+				Expect(server.ReceivedRequests()).To(HaveLen(2))
+			})
+		})
+	})
+
+	Context("when only the logging of the live rules has drifted", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v3/security_groups", "names=db"),
+				ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": [{
+					"guid": "db-guid",
+					"name": "db",
+					"rules": [
+						{"protocol": "tcp", "destination": "10.0.1.0/24", "ports": "5432", "log": true}
+					],
+					"globally_enabled": {"running": true, "staging": false}
+				}]}`),
+			))
+		})
+
+		It("reports the logged traffic as drift", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Out).To(gbytes.Say(`db: tcp 5432 \(log\): 0 addresses missing, 256 addresses unexpected\n- 10.0.1.0-10.0.1.255\n`))
+			Expect(sess.Out).NotTo(gbytes.Say("db: in sync"))
+			Expect(sess.Err).To(gbytes.Say("1 security group\\(s\\) have drifted"))
+		})
+	})
+
+	Context("when the config binds the security group to a space", func() {
+		var liveRunningSpaces string
+
+		BeforeEach(func() {
+			configYAML = `
+security_groups:
+- name: db
+  include:
+  - 10.0.1.0/24
+  protocols:
+  - protocol: tcp
+    ports: 5432
+  bind:
+    spaces:
+    - org: my-org
+      space: my-space
+      lifecycle: running
+`
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v3/security_groups", "names=db"),
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Write([]byte(`{"pagination": {"next": null}, "resources": [{
+							"guid": "db-guid",
+							"name": "db",
+							"rules": [{"protocol": "tcp", "destination": "10.0.1.0/24", "ports": "5432"}],
+							"globally_enabled": {"running": false, "staging": false},
+							"relationships": {
+								"running_spaces": {"data": ` + liveRunningSpaces + `},
+								"staging_spaces": {"data": []}
+							}
+						}]}`))
+					},
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v3/organizations", "names=my-org"),
+					ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": [{"guid": "org-guid"}]}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v3/spaces", "organization_guids=org-guid&names=my-space"),
+					ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": [{"guid": "space-guid"}]}`),
+				),
+			)
+		})
+
+		Context("and the live security group is bound to it", func() {
+			BeforeEach(func() {
+				liveRunningSpaces = `[{"guid": "space-guid"}]`
+			})
+
+			It("reports that it is in sync", func() {
+				Eventually(sess).Should(gexec.Exit(0))
+				Expect(sess.Out).To(gbytes.Say("db: in sync"))
+			})
+		})
+
+		Context("and the live security group is not bound to it", func() {
+			BeforeEach(func() {
+				liveRunningSpaces = `[{"guid": "other-space-guid"}]`
+			})
+
+			It("reports the missing binding as drift", func() {
+				Eventually(sess).Should(gexec.Exit(1))
+				Expect(sess.Out).To(gbytes.Say("db: not bound to space my-org/my-space for running"))
+				Expect(sess.Out).NotTo(gbytes.Say("db: in sync"))
+				Expect(sess.Err).To(gbytes.Say("1 security group\\(s\\) have drifted"))
+			})
+		})
+	})
+
+	Context("when the security group does not exist", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"pagination": {"next": null}, "resources": []}`))
+		})
+
+		It("reports it as drift", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Out).To(gbytes.Say("db: security group does not exist"))
+		})
+	})
+})
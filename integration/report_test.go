@@ -0,0 +1,119 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Report", func() {
+	var (
+		rulesFile *os.File
+		args      []string
+		sess      *gexec.Session
+	)
+
+	BeforeEach(func() {
+		var err error
+		rulesFile, err = ioutil.TempFile("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(rulesFile.Name(), []byte(`[
+			{"protocol": "all", "destination": "10.0.0.0-10.0.0.4"},
+			{"protocol": "all", "destination": "10.0.0.6-10.0.0.255"},
+			{"protocol": "tcp", "destination": "8.8.8.8", "ports": "53"}
+		]`), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		args = nil
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		sess, err = gexec.Start(exec.Command(binPath, append(append([]string{"report"}, args...), rulesFile.Name())...), GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(rulesFile.Name())
+	})
+
+	It("summarizes the allowed address space", func() {
+		Eventually(sess).Should(gexec.Exit(0))
+		Expect(sess.Out).To(gbytes.Say(`: 3 rules\n`))
+		Expect(sess.Out).To(gbytes.Say("Allowed addresses by protocol:"))
+		Expect(sess.Out).To(gbytes.Say(`  all: 2 rules, 255 addresses\n`))
+		Expect(sess.Out).To(gbytes.Say(`  tcp: 1 rule, 1 address\n`))
+		Expect(sess.Out).To(gbytes.Say("Address classes:"))
+		Expect(sess.Out).To(gbytes.Say(`  rfc1918: 255 of 17891328 addresses allowed \(<0.01%\)\n`))
+		Expect(sess.Out).To(gbytes.Say(`  public: 1 of \d+ addresses allowed \(<0.01%\)\n`))
+		Expect(sess.Out).To(gbytes.Say(`  unique-local: 0 of \d+ addresses allowed \(0.00%\)\n`))
+		Expect(sess.Out).To(gbytes.Say("Largest allowed blocks:"))
+		Expect(sess.Out).To(gbytes.Say(`  10.0.0.6-10.0.0.255 \(250 addresses\)\n`))
+		Expect(sess.Out).To(gbytes.Say(`  10.0.0.0-10.0.0.4 \(5 addresses\)\n`))
+		Expect(sess.Out).To(gbytes.Say(`  8.8.8.8 \(1 address\)\n`))
+		Expect(sess.Out).To(gbytes.Say("Denied gaps in private space:"))
+		Expect(sess.Out).To(gbytes.Say(`  10.0.0.5 \(1 address\)\n`))
+	})
+
+	Context("when given --top", func() {
+		BeforeEach(func() {
+			args = []string{"--top", "1"}
+		})
+
+		It("shows only that many blocks", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say("Largest allowed blocks:\n  10.0.0.6-10.0.0.255 \\(250 addresses\\)\nDenied gaps"))
+		})
+	})
+
+	Context("when given --json", func() {
+		BeforeEach(func() {
+			args = []string{"--json", "--top", "2"}
+		})
+
+		It("prints the report as JSON", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say(`"rules": 3`))
+			Expect(sess.Out).To(gbytes.Say(`"protocol": "all",\s+"rules": 2,\s+"addresses": 255`))
+			Expect(sess.Out).To(gbytes.Say(`"name": "rfc1918",\s+"size": 17891328,\s+"allowed": 255`))
+			Expect(sess.Out).To(gbytes.Say(`"largest_blocks": \[\s+"10.0.0.6-10.0.0.255",\s+"10.0.0.0-10.0.0.4"\s+\]`))
+			Expect(sess.Out).To(gbytes.Say(`"private_gaps": \[\s+"10.0.0.5"\s+\]`))
+		})
+	})
+
+	Context("when a special-purpose class is allowed entirely or all but one address", func() {
+		BeforeEach(func() {
+			err := ioutil.WriteFile(rulesFile.Name(), []byte(`[
+				{"protocol": "all", "destination": "169.254.0.0/16"},
+				{"protocol": "all", "destination": "240.0.0.0-255.255.255.254"}
+			]`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("reports each address family on its own and only a complete class as 100%", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say(`  link-local: 65536 of 65536 addresses allowed \(100.00%\)\n`))
+			Expect(sess.Out).To(gbytes.Say(`  link-local-ipv6: 0 of \d+ addresses allowed \(0.00%\)\n`))
+			Expect(sess.Out).To(gbytes.Say(`  reserved: 268435455 of 268435456 addresses allowed \(>99.99%\)\n`))
+		})
+	})
+
+	Context("when a destination is invalid", func() {
+		BeforeEach(func() {
+			err := ioutil.WriteFile(rulesFile.Name(), []byte(`[{"protocol": "all", "destination": "nope"}]`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("exits with an error", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("invalid destination 'nope'"))
+		})
+	})
+})
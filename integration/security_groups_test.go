@@ -0,0 +1,154 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Create with security groups", func() {
+	var (
+		configFile *os.File
+		outputDir  string
+		config     string
+		sess       *gexec.Session
+	)
+
+	JustBeforeEach(func() {
+		var err error
+		configFile, err = ioutil.TempFile("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(configFile.Name(), []byte(config), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		outputDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		cmd := exec.Command(binPath, "create", "--config", configFile.Name(), "--output-dir", filepath.Join(outputDir, "asgs"))
+		sess, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(configFile.Name())
+		os.RemoveAll(outputDir)
+	})
+
+	Context("when the config contains named security groups", func() {
+		BeforeEach(func() {
+			config = `
+exclude:
+- 10.0.0.5
+
+protocols:
+- protocol: tcp
+  ports: 5432
+
+security_groups:
+- name: dns
+  include:
+  - 10.0.0.2-10.0.0.5
+  protocols:
+  - protocol: udp
+    ports: 53
+- name: db-private
+  include:
+  - 10.0.0.0/29
+  exclude:
+  - 10.0.0.0-10.0.0.1
+`
+		})
+
+		It("writes one file per security group into the output directory", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say("Wrote " + filepath.Join(outputDir, "asgs", "dns.json")))
+			Expect(sess.Out).To(gbytes.Say("Wrote " + filepath.Join(outputDir, "asgs", "db-private.json")))
+
+			bs, err := ioutil.ReadFile(filepath.Join(outputDir, "asgs", "dns.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bs).To(MatchJSON(`[
+				{"protocol": "udp", "destination": "10.0.0.2-10.0.0.4", "ports": "53"}
+			]`))
+
+			bs, err = ioutil.ReadFile(filepath.Join(outputDir, "asgs", "db-private.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bs).To(MatchJSON(`[
+				{"protocol": "tcp", "destination": "10.0.0.2-10.0.0.4", "ports": "5432"},
+				{"protocol": "tcp", "destination": "10.0.0.6-10.0.0.7", "ports": "5432"}
+			]`))
+		})
+	})
+
+	Context("when the includes and excludes overlap or are adjacent", func() {
+		BeforeEach(func() {
+			config = `
+security_groups:
+- name: merged
+  include:
+  - 10.0.0.128/25
+  - 10.0.0.0/25
+  - 10.0.0.10-10.0.0.20
+  - 10.0.1.0
+  exclude:
+  - 10.0.0.100-10.0.0.110
+  - 10.0.0.105-10.0.0.120
+`
+		})
+
+		It("writes the minimum number of rules, sorted by address", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+
+			bs, err := ioutil.ReadFile(filepath.Join(outputDir, "asgs", "merged.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bs).To(MatchJSON(`[
+				{"protocol": "all", "destination": "10.0.0.0-10.0.0.99"},
+				{"protocol": "all", "destination": "10.0.0.121-10.0.1.0"}
+			]`))
+		})
+	})
+
+	Context("when a security group has a reversed range", func() {
+		BeforeEach(func() {
+			config = `
+security_groups:
+- name: dns
+  include:
+  - 10.0.0.2
+- name: db
+  include:
+  - 10.0.1.255-10.0.1.0
+`
+		})
+
+		It("reports the line and writes no files", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("line 8: include: invalid-range-start-after-end: 10.0.1.255-10.0.1.0"))
+
+			_, err := os.Stat(filepath.Join(outputDir, "asgs"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("when a security group is missing a name", func() {
+		BeforeEach(func() {
+			config = `
+security_groups:
+- include:
+  - 10.0.0.0/24
+`
+		})
+
+		It("exits non-zero", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Err).To(gbytes.Say("security_groups: entry 1: name is required"))
+		})
+	})
+})
@@ -0,0 +1,177 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+	var (
+		file     *os.File
+		contents string
+		sess     *gexec.Session
+	)
+
+	JustBeforeEach(func() {
+		var err error
+		file, err = ioutil.TempFile("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(file.Name(), []byte(contents), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		sess, err = gexec.Start(exec.Command(binPath, "validate", file.Name()), GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(file.Name())
+	})
+
+	Context("when given a valid rules file", func() {
+		BeforeEach(func() {
+			contents = `[
+				{"protocol": "all", "destination": "10.0.0.0-10.255.255.255"},
+				{"protocol": "tcp", "destination": "192.168.0.0/16", "ports": "443"},
+				{"protocol": "icmp", "destination": "172.16.0.0/12", "type": 0, "code": -1}
+			]`
+		})
+
+		It("exits zero", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say("OK"))
+		})
+	})
+
+	Context("when given an invalid rules file", func() {
+		BeforeEach(func() {
+			contents = `[
+				{"protocol": "all", "destination": "10.0.0.9-10.0.0.1"},
+				{"protocol": "sctp", "destination": "172.16.0.1"},
+				{"protocol": "tcp", "destination": "192.168.0.0/16", "ports": "80-"},
+				{"protocol": "all", "destination": "192.168.0.0-192.168.0.255"}
+			]`
+		})
+
+		It("reports each problem and exits non-zero", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Out).To(gbytes.Say("rule 1: invalid destination '10.0.0.9-10.0.0.1'"))
+			Expect(sess.Out).To(gbytes.Say("rule 2: invalid protocol 'sctp'"))
+			Expect(sess.Out).To(gbytes.Say("rule 3: invalid ports '80-'"))
+			Expect(sess.Out).To(gbytes.Say("rule 3: destination '192.168.0.0/16' overlaps rule 4 destination '192.168.0.0-192.168.0.255'"))
+			Expect(sess.Err).To(gbytes.Say("has 4 problem"))
+		})
+	})
+
+	Context("when given a valid create config", func() {
+		BeforeEach(func() {
+			contents = `
+include:
+- 10.68.192.0/24
+
+exclude:
+- 10.68.192.1-10.68.192.5
+`
+		})
+
+		It("exits zero", func() {
+			Eventually(sess).Should(gexec.Exit(0))
+			Expect(sess.Out).To(gbytes.Say("OK"))
+		})
+	})
+
+	Context("when given a create config with a reversed range", func() {
+		BeforeEach(func() {
+			contents = `
+exclude:
+- 10.68.192.9-10.68.192.1
+`
+		})
+
+		It("reports the problem and exits non-zero", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Out).To(gbytes.Say("exclude: invalid-range-start-after-end: 10.68.192.9-10.68.192.1"))
+		})
+	})
+
+	Context("when given a create config with several invalid ranges", func() {
+		BeforeEach(func() {
+			contents = `
+include: [10.0.0.0/8, "10.0.0.9-10.0.0.1"]
+
+exclude:
+- 10.0.0.1 # gateway
+- 10.0.0.5-fd00::1
+
+security_groups:
+- name: dns
+  include:
+  - fd00::1-fd00::ff
+  - 10.0.0.300
+  exclude:
+  - 10.0.0.0/33
+- name: db
+  include:
+  - 10.0.1.0/24
+`
+		})
+
+		It("reports every problem with its line number", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Out).To(gbytes.Say(`line 2: include: invalid-range-start-after-end: 10.0.0.9-10.0.0.1\n`))
+			Expect(sess.Out).To(gbytes.Say(`line 6: exclude: invalid-range-mixed-address-families: 10.0.0.5-fd00::1\n`))
+			Expect(sess.Out).To(gbytes.Say(`line 12: include: failed-to-parse-ip: 10.0.0.300\n`))
+			Expect(sess.Out).To(gbytes.Say(`line 14: exclude: invalid CIDR address: 10.0.0.0/33\n`))
+			Expect(sess.Err).To(gbytes.Say("has 4 problem"))
+		})
+	})
+
+	Context("when given a create config with flow sequences spanning lines", func() {
+		BeforeEach(func() {
+			contents = `
+include: [10.0.0.9-10.0.0.1,
+  10.0.1.0/24,
+  10.0.0.300]
+
+security_groups:
+- name: dns
+  exclude:
+    [10.0.0.1,
+     10.0.0.5-fd00::1]
+  include: [10.0.2.0/24]
+`
+		})
+
+		It("reports every problem with its line number", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Out).To(gbytes.Say(`line 2: include: invalid-range-start-after-end: 10.0.0.9-10.0.0.1\n`))
+			Expect(sess.Out).To(gbytes.Say(`line 4: include: failed-to-parse-ip: 10.0.0.300\n`))
+			Expect(sess.Out).To(gbytes.Say(`line 10: exclude: invalid-range-mixed-address-families: 10.0.0.5-fd00::1\n`))
+			Expect(sess.Err).To(gbytes.Say("has 3 problem"))
+		})
+	})
+
+	Context("when given a create config with invalid protocols", func() {
+		BeforeEach(func() {
+			contents = `
+protocols:
+- protocol: tcp
+- protocol: icmp
+  type: 0
+`
+		})
+
+		It("reports the problems and exits non-zero", func() {
+			Eventually(sess).Should(gexec.Exit(1))
+			Expect(sess.Out).To(gbytes.Say("protocols: entry 1: ports are required for protocol 'tcp'"))
+			Expect(sess.Out).To(gbytes.Say("protocols: entry 2: type and code are required for protocol 'icmp'"))
+		})
+	})
+})
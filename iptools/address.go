@@ -0,0 +1,75 @@
+package iptools
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// address is an IPv4 or IPv6 address as a 128-bit integer, which is much
+// cheaper to compare and step than a net.IP. IPv4 addresses only use lo.
+type address struct {
+	hi, lo uint64
+}
+
+// interval is an inclusive range of addresses of a single family, where
+// ipv4 selects the family.
+type interval struct {
+	start, end address
+	ipv4       bool
+}
+
+func toAddress(ip net.IP) (address, bool) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return address{lo: uint64(binary.BigEndian.Uint32(ip4))}, true
+	}
+
+	ip16 := ip.To16()
+	return address{
+		hi: binary.BigEndian.Uint64(ip16[:8]),
+		lo: binary.BigEndian.Uint64(ip16[8:]),
+	}, false
+}
+
+func (a address) ip(ipv4 bool) net.IP {
+	if ipv4 {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(a.lo))
+		return ip
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	binary.BigEndian.PutUint64(ip[:8], a.hi)
+	binary.BigEndian.PutUint64(ip[8:], a.lo)
+	return ip
+}
+
+func (a address) less(b address) bool {
+	return a.hi < b.hi || (a.hi == b.hi && a.lo < b.lo)
+}
+
+func (a address) inc() address {
+	if a.lo == ^uint64(0) {
+		return address{hi: a.hi + 1}
+	}
+
+	return address{hi: a.hi, lo: a.lo + 1}
+}
+
+func (a address) dec() address {
+	if a.lo == 0 {
+		return address{hi: a.hi - 1, lo: ^uint64(0)}
+	}
+
+	return address{hi: a.hi, lo: a.lo - 1}
+}
+
+func newInterval(r IPRange) interval {
+	start, ipv4 := toAddress(r.Start)
+	end, _ := toAddress(r.end())
+
+	return interval{start: start, end: end, ipv4: ipv4}
+}
+
+func (i interval) ipRange() IPRange {
+	return newIPRange(i.start.ip(i.ipv4), i.end.ip(i.ipv4))
+}
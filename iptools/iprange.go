@@ -2,7 +2,9 @@ package iptools
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"net"
 	"strings"
 )
@@ -18,6 +20,21 @@ func (r *IPRange) UnmarshalYAML(tag string, value interface{}) error {
 		return fmt.Errorf("failed-to-unmarshal-iprange-from-value: '%v'", value)
 	}
 
+	ipRange, err := ParseIPRange(data)
+	if err != nil {
+		return err
+	}
+
+	if err := ipRange.Validate(); err != nil {
+		return err
+	}
+
+	*r = ipRange
+	return nil
+}
+
+// ParseIPRange parses a single IP, a CIDR or a hyphenated IP range.
+func ParseIPRange(data string) (IPRange, error) {
 	dataWithoutSpaces := strings.Replace(data, " ", "", -1)
 	idx := strings.IndexAny(dataWithoutSpaces, "-/")
 
@@ -25,46 +42,37 @@ func (r *IPRange) UnmarshalYAML(tag string, value interface{}) error {
 	if idx == -1 {
 		ip := net.ParseIP(dataWithoutSpaces)
 		if ip == nil {
-			return fmt.Errorf("failed-to-parse-ip: %s", data)
+			return IPRange{}, fmt.Errorf("failed-to-parse-ip: %s", data)
 		}
-		*r = IPRange{Start: ip}
-		return nil
+		return IPRange{Start: ip}, nil
 	}
 
 	// CIDR
 	if dataWithoutSpaces[idx] == '/' {
 		_, ipNet, err := net.ParseCIDR(dataWithoutSpaces)
 		if err != nil {
-			return err
+			return IPRange{}, err
 		}
-		*r = NewIPRangeFromIPNet(ipNet)
-		return nil
+		return NewIPRangeFromIPNet(ipNet), nil
 	}
 
 	// hyphenated range
-	if dataWithoutSpaces[idx] == '-' {
-		startIP := net.ParseIP(dataWithoutSpaces[:idx])
-		endIP := net.ParseIP(dataWithoutSpaces[idx+1:])
+	startIP := net.ParseIP(dataWithoutSpaces[:idx])
+	endIP := net.ParseIP(dataWithoutSpaces[idx+1:])
 
-		if startIP == nil || endIP == nil {
-			return fmt.Errorf("failed-to-parse-range: %s", data)
-		}
-
-		*r = IPRange{
-			Start: startIP,
-			End:   endIP,
-		}
+	if startIP == nil || endIP == nil {
+		return IPRange{}, fmt.Errorf("failed-to-parse-range: %s", data)
 	}
 
-	return nil
+	return IPRange{
+		Start: startIP,
+		End:   endIP,
+	}, nil
 }
 
 func NewIPRangeFromIPNet(ipNet *net.IPNet) IPRange {
 	min, max := NetworkRange(ipNet)
-	return IPRange{
-		Start: min,
-		End:   max,
-	}
+	return newIPRange(min, max)
 }
 
 func (r *IPRange) String() string {
@@ -75,6 +83,76 @@ func (r *IPRange) String() string {
 	return fmt.Sprintf("%s-%s", r.Start, r.End)
 }
 
+func (r IPRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// Size returns the number of addresses in the range.
+func (r *IPRange) Size() *big.Int {
+	size := new(big.Int).Sub(
+		new(big.Int).SetBytes(normalizeIP(r.end())),
+		new(big.Int).SetBytes(normalizeIP(r.Start)),
+	)
+
+	return size.Add(size, big.NewInt(1))
+}
+
+// CIDRs decomposes the range into the minimal set of CIDR blocks covering it.
+func (r *IPRange) CIDRs() []*net.IPNet {
+	start := CopyIP(normalizeIP(r.Start))
+	end := normalizeIP(r.end())
+	bits := len(start) * 8
+
+	var ipNets []*net.IPNet
+	for {
+		ones := bits
+		for ones > 0 {
+			mask := net.CIDRMask(ones-1, bits)
+			if !start.Mask(mask).Equal(start) {
+				break
+			}
+
+			_, max := NetworkRange(&net.IPNet{IP: start, Mask: mask})
+			if bytes.Compare(max, end) > 0 {
+				break
+			}
+
+			ones--
+		}
+
+		ipNet := &net.IPNet{IP: start, Mask: net.CIDRMask(ones, bits)}
+		ipNets = append(ipNets, ipNet)
+
+		_, max := NetworkRange(ipNet)
+		if bytes.Compare(max, end) >= 0 {
+			return ipNets
+		}
+
+		start = Inc(max)
+	}
+}
+
+// Validate returns an error if the range ends before it starts or mixes
+// IPv4 and IPv6 addresses.
+func (r *IPRange) Validate() error {
+	if r.SingleIP() {
+		return nil
+	}
+
+	start := normalizeIP(r.Start)
+	end := normalizeIP(r.End)
+
+	if len(start) != len(end) {
+		return fmt.Errorf("invalid-range-mixed-address-families: %s", r)
+	}
+
+	if bytes.Compare(start, end) > 0 {
+		return fmt.Errorf("invalid-range-start-after-end: %s", r)
+	}
+
+	return nil
+}
+
 func (r *IPRange) StartsAt(ip net.IP) bool {
 	return r.Start.Equal(ip)
 }
@@ -88,7 +166,23 @@ func (r *IPRange) SingleIP() bool {
 }
 
 func (r *IPRange) Contains(ip net.IP) bool {
-	return bytes.Compare(r.Start.To4(), ip.To4()) <= 0 && bytes.Compare(ip.To4(), r.End.To4()) <= 0
+	start := normalizeIP(r.Start)
+	end := normalizeIP(r.end())
+	ip = normalizeIP(ip)
+
+	if len(start) != len(ip) || len(end) != len(ip) {
+		return false
+	}
+
+	return bytes.Compare(start, ip) <= 0 && bytes.Compare(ip, end) <= 0
+}
+
+func (r *IPRange) end() net.IP {
+	if r.SingleIP() {
+		return r.Start
+	}
+
+	return r.End
 }
 
 func (r *IPRange) OverlapsNet(ipNet *net.IPNet) bool {
@@ -111,21 +205,15 @@ func (r *IPRange) OverlapsRange(other IPRange) bool {
 		r.Contains(other.Start) || r.Contains(other.End)
 }
 
+// SliceRanges returns the parts of the range not covered by any of the given
+// ranges, in order.
 func (r *IPRange) SliceRanges(ipRanges []IPRange) []IPRange {
-	rs := []IPRange{*r}
-
-	for i := range ipRanges {
-		var newRanges []IPRange
-		for j := range rs {
-			newRanges = append(newRanges, rs[j].SliceRange(ipRanges[i])...)
-		}
-		rs = newRanges
-	}
-
-	return rs
+	return NewIPSet(*r).Difference(NewIPSet(ipRanges...)).Ranges()
 }
 
 func (r *IPRange) SliceIP(ip net.IP) []IPRange {
+	ip = normalizeIP(ip)
+
 	if !r.Contains(ip) {
 		return []IPRange{*r}
 	}
@@ -197,30 +285,42 @@ func (r *IPRange) SliceRange(other IPRange) []IPRange {
 		return nil
 	}
 
-	if r.SingleIP() || other.SingleIP() {
+	if r.SingleIP() {
+		if other.Contains(r.Start) {
+			return nil
+		}
+		return []IPRange{*r}
+	}
+
+	if other.SingleIP() {
 		return r.SliceIP(other.Start)
 	}
 
-	thisStart := r.Start.To4()
-	thisEnd := r.End.To4()
+	thisStart := normalizeIP(r.Start)
+	thisEnd := normalizeIP(r.End)
 
-	otherStart := other.Start.To4()
-	otherEnd := other.End.To4()
+	otherStart := normalizeIP(other.Start)
+	otherEnd := normalizeIP(other.End)
 
 	var ipRanges []IPRange
 	if bytes.Compare(thisStart, otherStart) == -1 {
-		ipRanges = append(ipRanges, IPRange{
-			Start: thisStart,
-			End:   Dec(otherStart),
-		})
+		ipRanges = append(ipRanges, newIPRange(thisStart, Dec(otherStart)))
 	}
 
 	if bytes.Compare(thisEnd, otherEnd) == 1 {
-		ipRanges = append(ipRanges, IPRange{
-			Start: Inc(otherEnd),
-			End:   thisEnd,
-		})
+		ipRanges = append(ipRanges, newIPRange(Inc(otherEnd), thisEnd))
 	}
 
 	return ipRanges
 }
+
+func newIPRange(start, end net.IP) IPRange {
+	if start.Equal(end) {
+		return IPRange{Start: start}
+	}
+
+	return IPRange{
+		Start: start,
+		End:   end,
+	}
+}
@@ -78,6 +78,258 @@ var _ = Describe("IPRange", func() {
 		})
 	})
 
+	Describe("Contains with IPv6", func() {
+		var ipRange = iptools.IPRange{
+			Start: net.ParseIP("2001:db8::"),
+			End:   net.ParseIP("2001:db8::ffff"),
+		}
+
+		It("returns true when the IPRange contains the IP", func() {
+			Expect(ipRange.Contains(net.ParseIP("2001:db8::1"))).To(BeTrue())
+		})
+
+		It("returns false when the IP is outside the IPRange", func() {
+			Expect(ipRange.Contains(net.ParseIP("2001:db8::1:0"))).To(BeFalse())
+		})
+
+		It("returns false for an IPv4 address", func() {
+			Expect(ipRange.Contains(net.IP{10, 0, 0, 1})).To(BeFalse())
+		})
+
+		It("returns true for a single IP range containing the IP", func() {
+			singleIP := iptools.IPRange{Start: net.ParseIP("2001:db8::1")}
+			Expect(singleIP.Contains(net.ParseIP("2001:db8::1"))).To(BeTrue())
+		})
+	})
+
+	Describe("SliceRange", func() {
+		Context("when the ranges are IPv6", func() {
+			It("removes the overlap", func() {
+				ipRange := iptools.IPRange{
+					Start: net.ParseIP("2001:db8::"),
+					End:   net.ParseIP("2001:db8::ffff"),
+				}
+
+				result := ipRange.SliceRange(iptools.IPRange{
+					Start: net.ParseIP("2001:db8::100"),
+					End:   net.ParseIP("2001:db8::1ff"),
+				})
+
+				Expect(result).To(HaveLen(2))
+				Expect(result[0].String()).To(Equal("2001:db8::-2001:db8::ff"))
+				Expect(result[1].String()).To(Equal("2001:db8::200-2001:db8::ffff"))
+			})
+		})
+
+		Context("when the ranges are of different address families", func() {
+			It("returns the original range", func() {
+				ipRange := iptools.IPRange{
+					Start: net.IP{0, 0, 0, 0},
+					End:   net.IP{255, 255, 255, 255},
+				}
+
+				result := ipRange.SliceRange(iptools.IPRange{
+					Start: net.ParseIP("::"),
+					End:   net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+				})
+
+				Expect(result).To(Equal([]iptools.IPRange{ipRange}))
+			})
+		})
+
+		Context("when a single IP range is contained in the other range", func() {
+			It("returns nil", func() {
+				ipRange := iptools.IPRange{Start: net.ParseIP("2001:db8::5")}
+
+				result := ipRange.SliceRange(iptools.IPRange{
+					Start: net.ParseIP("2001:db8::"),
+					End:   net.ParseIP("2001:db8::ffff"),
+				})
+
+				Expect(result).To(BeNil())
+			})
+		})
+	})
+
+	Describe("SliceRanges", func() {
+		rangeStrings := func(ipRanges []iptools.IPRange) []string {
+			var strs []string
+			for i := range ipRanges {
+				strs = append(strs, ipRanges[i].String())
+			}
+			return strs
+		}
+
+		It("removes unsorted, overlapping and adjacent ranges", func() {
+			ipRange := iptools.IPRange{
+				Start: net.IP{10, 0, 0, 0},
+				End:   net.IP{10, 0, 0, 255},
+			}
+
+			result := ipRange.SliceRanges([]iptools.IPRange{
+				{Start: net.IP{10, 0, 0, 200}, End: net.IP{10, 0, 1, 10}},
+				{Start: net.IP{10, 0, 0, 10}, End: net.IP{10, 0, 0, 20}},
+				{Start: net.IP{10, 0, 0, 15}, End: net.IP{10, 0, 0, 30}},
+				{Start: net.IP{10, 0, 0, 31}},
+				{Start: net.IP{9, 0, 0, 0}, End: net.IP{10, 0, 0, 0}},
+				{Start: net.ParseIP("::a00:0"), End: net.ParseIP("::a00:ff")},
+			})
+
+			Expect(rangeStrings(result)).To(Equal([]string{
+				"10.0.0.1-10.0.0.9",
+				"10.0.0.32-10.0.0.199",
+			}))
+		})
+
+		It("returns the original range when nothing overlaps", func() {
+			ipRange := iptools.IPRange{
+				Start: net.IP{10, 0, 0, 0},
+				End:   net.IP{10, 0, 0, 255},
+			}
+
+			result := ipRange.SliceRanges([]iptools.IPRange{{Start: net.IP{10, 0, 1, 0}}})
+			Expect(result).To(Equal([]iptools.IPRange{ipRange}))
+		})
+
+		It("returns nil when the range is covered", func() {
+			ipRange := iptools.IPRange{Start: net.ParseIP("2001:db8::5")}
+
+			result := ipRange.SliceRanges([]iptools.IPRange{
+				{Start: net.ParseIP("2001:db8::"), End: net.ParseIP("2001:db8::ffff")},
+			})
+			Expect(result).To(BeNil())
+		})
+
+		It("handles the ends of the IPv6 address space", func() {
+			ipRange := iptools.IPRange{
+				Start: net.ParseIP("::"),
+				End:   net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+			}
+
+			result := ipRange.SliceRanges([]iptools.IPRange{
+				{Start: net.ParseIP("::")},
+				{Start: net.ParseIP("::ffff:ffff:ffff:ffff"), End: net.ParseIP("::1:0:0:0:0")},
+				{Start: net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff")},
+			})
+
+			Expect(rangeStrings(result)).To(Equal([]string{
+				"::1-::ffff:ffff:ffff:fffe",
+				"::1:0:0:0:1-ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe",
+			}))
+		})
+
+		It("agrees with slicing out each range in turn", func() {
+			ipRange := iptools.IPRange{
+				Start: net.IP{10, 0, 0, 0},
+				End:   net.IP{10, 0, 3, 255},
+			}
+
+			var excludes []iptools.IPRange
+			for i := 0; i < 64; i++ {
+				start := net.IP{10, 0, byte(i * 37 % 5), byte(i * 53 % 256)}
+				excludes = append(excludes, iptools.IPRange{Start: start, End: iptools.Inc(iptools.Inc(start))})
+			}
+
+			expected := []iptools.IPRange{ipRange}
+			for i := range excludes {
+				var sliced []iptools.IPRange
+				for j := range expected {
+					sliced = append(sliced, expected[j].SliceRange(excludes[i])...)
+				}
+				expected = sliced
+			}
+
+			Expect(rangeStrings(ipRange.SliceRanges(excludes))).To(ConsistOf(rangeStrings(expected)))
+		})
+	})
+
+	Describe("CIDRs", func() {
+		cidrStrings := func(ipRange iptools.IPRange) []string {
+			var cidrs []string
+			for _, ipNet := range ipRange.CIDRs() {
+				cidrs = append(cidrs, ipNet.String())
+			}
+			return cidrs
+		}
+
+		Context("when the range is exactly a CIDR block", func() {
+			It("returns that block", func() {
+				Expect(cidrStrings(iptools.IPRange{
+					Start: net.IP{10, 10, 0, 0},
+					End:   net.IP{10, 10, 255, 255},
+				})).To(Equal([]string{"10.10.0.0/16"}))
+			})
+		})
+
+		Context("when the range is a single IP", func() {
+			It("returns a host block", func() {
+				Expect(cidrStrings(iptools.IPRange{
+					Start: net.IP{10, 10, 0, 1},
+				})).To(Equal([]string{"10.10.0.1/32"}))
+			})
+		})
+
+		Context("when the range is not aligned to a CIDR block", func() {
+			It("returns the minimal set of blocks", func() {
+				Expect(cidrStrings(iptools.IPRange{
+					Start: net.IP{11, 0, 0, 0},
+					End:   net.IP{169, 253, 255, 255},
+				})).To(Equal([]string{
+					"11.0.0.0/8",
+					"12.0.0.0/6",
+					"16.0.0.0/4",
+					"32.0.0.0/3",
+					"64.0.0.0/2",
+					"128.0.0.0/3",
+					"160.0.0.0/5",
+					"168.0.0.0/8",
+					"169.0.0.0/9",
+					"169.128.0.0/10",
+					"169.192.0.0/11",
+					"169.224.0.0/12",
+					"169.240.0.0/13",
+					"169.248.0.0/14",
+					"169.252.0.0/15",
+				}))
+			})
+		})
+
+		Context("when the range spans the entire address space", func() {
+			It("returns a single block", func() {
+				Expect(cidrStrings(iptools.IPRange{
+					Start: net.IP{0, 0, 0, 0},
+					End:   net.IP{255, 255, 255, 255},
+				})).To(Equal([]string{"0.0.0.0/0"}))
+			})
+		})
+
+		Context("when the range is IPv6", func() {
+			It("returns IPv6 blocks", func() {
+				Expect(cidrStrings(iptools.IPRange{
+					Start: net.ParseIP("2001:db8::1"),
+					End:   net.ParseIP("2001:db8::ffff"),
+				})).To(Equal([]string{
+					"2001:db8::1/128",
+					"2001:db8::2/127",
+					"2001:db8::4/126",
+					"2001:db8::8/125",
+					"2001:db8::10/124",
+					"2001:db8::20/123",
+					"2001:db8::40/122",
+					"2001:db8::80/121",
+					"2001:db8::100/120",
+					"2001:db8::200/119",
+					"2001:db8::400/118",
+					"2001:db8::800/117",
+					"2001:db8::1000/116",
+					"2001:db8::2000/115",
+					"2001:db8::4000/114",
+					"2001:db8::8000/113",
+				}))
+			})
+		})
+	})
+
 	Describe("SliceIP", func() {
 		var (
 			ip     net.IP
@@ -193,6 +445,45 @@ var _ = Describe("IPRange", func() {
 		})
 	})
 
+	Describe("Size", func() {
+		It("returns 1 for a single IP", func() {
+			ipRange := iptools.IPRange{Start: net.IP{10, 0, 0, 1}}
+			Expect(ipRange.Size().String()).To(Equal("1"))
+		})
+
+		It("returns the number of addresses in an IPv4 range", func() {
+			ipRange := iptools.IPRange{Start: net.IP{10, 0, 0, 0}, End: net.IP{10, 0, 1, 255}}
+			Expect(ipRange.Size().String()).To(Equal("512"))
+		})
+
+		It("returns the number of addresses in an IPv6 range", func() {
+			ipRange := iptools.IPRange{Start: net.ParseIP("::"), End: net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff")}
+			Expect(ipRange.Size().String()).To(Equal("340282366920938463463374607431768211456"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("accepts a single IP", func() {
+			ipRange := iptools.IPRange{Start: net.IP{10, 0, 0, 1}}
+			Expect(ipRange.Validate()).To(Succeed())
+		})
+
+		It("accepts a range", func() {
+			ipRange := iptools.IPRange{Start: net.IP{10, 0, 0, 1}, End: net.IP{10, 0, 0, 9}}
+			Expect(ipRange.Validate()).To(Succeed())
+		})
+
+		It("rejects a reversed range", func() {
+			ipRange := iptools.IPRange{Start: net.IP{10, 0, 0, 9}, End: net.IP{10, 0, 0, 1}}
+			Expect(ipRange.Validate()).To(MatchError("invalid-range-start-after-end: 10.0.0.9-10.0.0.1"))
+		})
+
+		It("rejects a range mixing address families", func() {
+			ipRange := iptools.IPRange{Start: net.IP{10, 0, 0, 1}, End: net.ParseIP("2001:db8::1")}
+			Expect(ipRange.Validate()).To(MatchError("invalid-range-mixed-address-families: 10.0.0.1-2001:db8::1"))
+		})
+	})
+
 	Describe("UnmarshalYAML", func() {
 		var testStruct TestStruct
 		var decodeErr error
@@ -232,6 +523,34 @@ ip_range: 192.168.1.1 - 192.168.1.3
 			})
 		})
 
+		Context("when given an IPv6 range", func() {
+			BeforeEach(func() {
+				yaml = `
+ip_range: 2001:db8::1-2001:db8::ff
+`
+			})
+
+			It("populates the IPRange properly", func() {
+				Expect(decodeErr).NotTo(HaveOccurred())
+				Expect(testStruct.IPRange.Start.Equal(net.ParseIP("2001:db8::1"))).To(BeTrue())
+				Expect(testStruct.IPRange.End.Equal(net.ParseIP("2001:db8::ff"))).To(BeTrue())
+			})
+		})
+
+		Context("when given an IPv6 CIDR", func() {
+			BeforeEach(func() {
+				yaml = `
+ip_range: 2001:db8::/112
+`
+			})
+
+			It("populates the IPRange properly", func() {
+				Expect(decodeErr).NotTo(HaveOccurred())
+				Expect(testStruct.IPRange.Start.Equal(net.ParseIP("2001:db8::"))).To(BeTrue())
+				Expect(testStruct.IPRange.End.Equal(net.ParseIP("2001:db8::ffff"))).To(BeTrue())
+			})
+		})
+
 		Context("when given invalid syntax", func() {
 			BeforeEach(func() {
 				yaml = `
@@ -244,6 +563,30 @@ ip_range: 192.168.1.1/192.168.1.3
 			})
 		})
 
+		Context("when given a reversed range", func() {
+			BeforeEach(func() {
+				yaml = `
+ip_range: 10.0.0.9-10.0.0.1
+`
+			})
+
+			It("returns an error", func() {
+				Expect(decodeErr).To(MatchError(ContainSubstring("invalid-range-start-after-end: 10.0.0.9-10.0.0.1")))
+			})
+		})
+
+		Context("when given a range mixing address families", func() {
+			BeforeEach(func() {
+				yaml = `
+ip_range: 10.0.0.1-2001:db8::1
+`
+			})
+
+			It("returns an error", func() {
+				Expect(decodeErr).To(MatchError(ContainSubstring("invalid-range-mixed-address-families: 10.0.0.1-2001:db8::1")))
+			})
+		})
+
 		Context("when given an invalid value", func() {
 			BeforeEach(func() {
 				yaml = `
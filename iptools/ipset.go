@@ -0,0 +1,193 @@
+package iptools
+
+import (
+	"math/big"
+	"net"
+	"sort"
+)
+
+var (
+	ipv4Space = interval{start: address{}, end: address{lo: 1<<32 - 1}, ipv4: true}
+	ipv6Space = interval{start: address{}, end: address{hi: ^uint64(0), lo: ^uint64(0)}}
+)
+
+// IPSet is an immutable set of IPv4 and IPv6 addresses, stored as sorted,
+// disjoint and non-adjacent ranges with IPv4 ranges first.
+type IPSet struct {
+	intervals []interval
+}
+
+func NewIPSet(ipRanges ...IPRange) IPSet {
+	intervals := make([]interval, len(ipRanges))
+	for i := range ipRanges {
+		intervals[i] = newInterval(ipRanges[i])
+	}
+
+	return IPSet{intervals: mergeIntervals(intervals)}
+}
+
+// Merge returns the minimal sorted list of ranges covering the same addresses
+// as the given ranges, combining ranges that overlap or are adjacent. IPv4
+// ranges are sorted before IPv6 ranges.
+func Merge(ipRanges []IPRange) []IPRange {
+	return NewIPSet(ipRanges...).Ranges()
+}
+
+// Ranges returns the minimal sorted list of ranges in the set.
+func (s IPSet) Ranges() []IPRange {
+	var ipRanges []IPRange
+	for _, i := range s.intervals {
+		ipRanges = append(ipRanges, i.ipRange())
+	}
+
+	return ipRanges
+}
+
+// CIDRs returns the minimal list of CIDR blocks covering the set.
+func (s IPSet) CIDRs() []*net.IPNet {
+	var ipNets []*net.IPNet
+	for _, ipRange := range s.Ranges() {
+		ipNets = append(ipNets, ipRange.CIDRs()...)
+	}
+
+	return ipNets
+}
+
+func (s IPSet) Empty() bool {
+	return len(s.intervals) == 0
+}
+
+func (s IPSet) Size() *big.Int {
+	size := new(big.Int)
+	for _, ipRange := range s.Ranges() {
+		size.Add(size, ipRange.Size())
+	}
+
+	return size
+}
+
+func (s IPSet) Contains(ip net.IP) bool {
+	a, ipv4 := toAddress(ip)
+
+	i := sort.Search(len(s.intervals), func(i int) bool {
+		if s.intervals[i].ipv4 != ipv4 {
+			return !s.intervals[i].ipv4
+		}
+		return !s.intervals[i].end.less(a)
+	})
+
+	return i < len(s.intervals) && s.intervals[i].ipv4 == ipv4 && !a.less(s.intervals[i].start)
+}
+
+func (s IPSet) Union(other IPSet) IPSet {
+	intervals := make([]interval, 0, len(s.intervals)+len(other.intervals))
+	intervals = append(intervals, s.intervals...)
+	intervals = append(intervals, other.intervals...)
+
+	return IPSet{intervals: mergeIntervals(intervals)}
+}
+
+func (s IPSet) Intersect(other IPSet) IPSet {
+	var intervals []interval
+
+	i, j := 0, 0
+	for i < len(s.intervals) && j < len(other.intervals) {
+		a, b := s.intervals[i], other.intervals[j]
+
+		if a.ipv4 != b.ipv4 {
+			// IPv4 sorts first, so the IPv4 side has no counterpart left
+			if a.ipv4 {
+				i++
+			} else {
+				j++
+			}
+			continue
+		}
+
+		start, end := a.start, a.end
+		if start.less(b.start) {
+			start = b.start
+		}
+		if b.end.less(end) {
+			end = b.end
+		}
+		if !end.less(start) {
+			intervals = append(intervals, interval{start: start, end: end, ipv4: a.ipv4})
+		}
+
+		if a.end.less(b.end) {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return IPSet{intervals: intervals}
+}
+
+func (s IPSet) Difference(other IPSet) IPSet {
+	return s.Intersect(other.Complement())
+}
+
+// Complement returns every IPv4 and IPv6 address not in the set.
+func (s IPSet) Complement() IPSet {
+	var intervals []interval
+
+	for _, space := range []interval{ipv4Space, ipv6Space} {
+		next := space.start
+		done := false
+
+		for _, i := range s.intervals {
+			if i.ipv4 != space.ipv4 {
+				continue
+			}
+
+			if next.less(i.start) {
+				intervals = append(intervals, interval{start: next, end: i.start.dec(), ipv4: space.ipv4})
+			}
+
+			if i.end == space.end {
+				done = true
+				break
+			}
+			next = i.end.inc()
+		}
+
+		if !done {
+			intervals = append(intervals, interval{start: next, end: space.end, ipv4: space.ipv4})
+		}
+	}
+
+	return IPSet{intervals: intervals}
+}
+
+// mergeIntervals sorts the intervals, IPv4 first, and combines those that
+// overlap or are adjacent.
+func mergeIntervals(intervals []interval) []interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		if intervals[i].ipv4 != intervals[j].ipv4 {
+			return intervals[i].ipv4
+		}
+		return intervals[i].start.less(intervals[j].start)
+	})
+
+	merged := []interval{intervals[0]}
+	for _, next := range intervals[1:] {
+		current := &merged[len(merged)-1]
+
+		if next.ipv4 == current.ipv4 && (!current.end.less(next.start) || current.end.inc() == next.start) {
+			if current.end.less(next.end) {
+				current.end = next.end
+			}
+			continue
+		}
+
+		merged = append(merged, next)
+	}
+
+	return merged
+}
@@ -0,0 +1,64 @@
+package iptools_test
+
+import (
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+)
+
+func randomExcludes(n int) []iptools.IPRange {
+	r := rand.New(rand.NewSource(1))
+
+	excludes := make([]iptools.IPRange, n)
+	for i := range excludes {
+		start := net.IP{10, byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256))}
+		if i%2 == 0 {
+			excludes[i] = iptools.IPRange{Start: start}
+		} else {
+			excludes[i] = iptools.IPRange{Start: start, End: iptools.Inc(iptools.Inc(start))}
+		}
+	}
+
+	return excludes
+}
+
+func benchmarkDifference(b *testing.B, n int) {
+	all := iptools.NewIPSet(iptools.IPRange{
+		Start: net.IP{10, 0, 0, 0},
+		End:   net.IP{10, 255, 255, 255},
+	})
+	excludes := randomExcludes(n)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		all.Difference(iptools.NewIPSet(excludes...))
+	}
+}
+
+func BenchmarkDifference1k(b *testing.B)   { benchmarkDifference(b, 1000) }
+func BenchmarkDifference10k(b *testing.B)  { benchmarkDifference(b, 10000) }
+func BenchmarkDifference100k(b *testing.B) { benchmarkDifference(b, 100000) }
+
+func BenchmarkSliceRanges100k(b *testing.B) {
+	ipRange := iptools.IPRange{
+		Start: net.IP{10, 0, 0, 0},
+		End:   net.IP{10, 255, 255, 255},
+	}
+	excludes := randomExcludes(100000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ipRange.SliceRanges(excludes)
+	}
+}
+
+func BenchmarkMerge100k(b *testing.B) {
+	excludes := randomExcludes(100000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		iptools.Merge(excludes)
+	}
+}
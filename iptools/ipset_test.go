@@ -0,0 +1,160 @@
+package iptools_test
+
+import (
+	"math/big"
+	"net"
+
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IPSet", func() {
+	newIPSet := func(strs ...string) iptools.IPSet {
+		var ipRanges []iptools.IPRange
+		for _, str := range strs {
+			ipRange, err := iptools.ParseIPRange(str)
+			Expect(err).NotTo(HaveOccurred())
+			ipRanges = append(ipRanges, ipRange)
+		}
+		return iptools.NewIPSet(ipRanges...)
+	}
+
+	rangeStrings := func(s iptools.IPSet) []string {
+		var strs []string
+		for _, ipRange := range s.Ranges() {
+			strs = append(strs, ipRange.String())
+		}
+		return strs
+	}
+
+	It("stores sorted, merged ranges", func() {
+		s := newIPSet("10.0.1.0/24", "2001:db8::/64", "10.0.0.0/24", "10.0.0.5")
+		Expect(rangeStrings(s)).To(Equal([]string{
+			"10.0.0.0-10.0.1.255",
+			"2001:db8::-2001:db8::ffff:ffff:ffff:ffff",
+		}))
+	})
+
+	Describe("Union", func() {
+		It("contains the addresses of both sets", func() {
+			s := newIPSet("10.0.0.0-10.0.0.9", "10.0.0.20-10.0.0.29").Union(newIPSet("10.0.0.10-10.0.0.14", "::1"))
+			Expect(rangeStrings(s)).To(Equal([]string{"10.0.0.0-10.0.0.14", "10.0.0.20-10.0.0.29", "::1"}))
+		})
+	})
+
+	Describe("Intersect", func() {
+		It("contains the addresses in both sets", func() {
+			s := newIPSet("10.0.0.0-10.0.0.9", "10.0.0.20-10.0.0.29", "2001:db8::/120").
+				Intersect(newIPSet("10.0.0.5-10.0.0.24", "10.0.0.29", "2001:db8::ff-2001:db8::1ff"))
+			Expect(rangeStrings(s)).To(Equal([]string{
+				"10.0.0.5-10.0.0.9",
+				"10.0.0.20-10.0.0.24",
+				"10.0.0.29",
+				"2001:db8::ff",
+			}))
+		})
+
+		It("is empty for sets of different address families", func() {
+			Expect(newIPSet("0.0.0.0/0").Intersect(newIPSet("::/0")).Empty()).To(BeTrue())
+		})
+	})
+
+	Describe("Difference", func() {
+		It("contains the addresses only in the first set", func() {
+			s := newIPSet("10.0.0.0/24", "::/0").Difference(newIPSet("10.0.0.10-10.0.0.19", "10.0.0.255", "::/1"))
+			Expect(rangeStrings(s)).To(Equal([]string{
+				"10.0.0.0-10.0.0.9",
+				"10.0.0.20-10.0.0.254",
+				"8000::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+			}))
+		})
+
+		It("handles the ends of the IPv6 address space", func() {
+			s := newIPSet("::/0").Difference(newIPSet(
+				"::",
+				"::ffff:ffff:ffff:ffff-::1:0:0:0:0",
+				"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+			))
+			Expect(rangeStrings(s)).To(Equal([]string{
+				"::1-::ffff:ffff:ffff:fffe",
+				"::1:0:0:0:1-ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe",
+			}))
+		})
+
+		It("agrees with slicing out each range in turn", func() {
+			ipRange := iptools.IPRange{
+				Start: net.IP{10, 0, 0, 0},
+				End:   net.IP{10, 0, 3, 255},
+			}
+
+			var excludes []iptools.IPRange
+			for i := 0; i < 64; i++ {
+				start := net.IP{10, 0, byte(i * 37 % 5), byte(i * 53 % 256)}
+				excludes = append(excludes, iptools.IPRange{Start: start, End: iptools.Inc(iptools.Inc(start))})
+			}
+
+			expected := []iptools.IPRange{ipRange}
+			for i := range excludes {
+				var sliced []iptools.IPRange
+				for j := range expected {
+					sliced = append(sliced, expected[j].SliceRange(excludes[i])...)
+				}
+				expected = sliced
+			}
+
+			s := iptools.NewIPSet(ipRange).Difference(iptools.NewIPSet(excludes...))
+			Expect(s.Ranges()).To(Equal(iptools.Merge(expected)))
+		})
+	})
+
+	Describe("Complement", func() {
+		It("contains every other IPv4 and IPv6 address", func() {
+			s := newIPSet("0.0.0.0", "10.0.0.0/8", "255.255.255.255", "::/1").Complement()
+			Expect(rangeStrings(s)).To(Equal([]string{
+				"0.0.0.1-9.255.255.255",
+				"11.0.0.0-255.255.255.254",
+				"8000::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+			}))
+		})
+
+		It("is everything for the empty set", func() {
+			Expect(rangeStrings(iptools.NewIPSet().Complement())).To(Equal([]string{
+				"0.0.0.0-255.255.255.255",
+				"::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+			}))
+		})
+	})
+
+	Describe("Contains", func() {
+		s := newIPSet("10.0.0.0-10.0.0.9", "10.0.0.20", "2001:db8::/64")
+
+		It("returns whether the address is in the set", func() {
+			Expect(s.Contains(net.ParseIP("10.0.0.0"))).To(BeTrue())
+			Expect(s.Contains(net.ParseIP("10.0.0.9"))).To(BeTrue())
+			Expect(s.Contains(net.ParseIP("10.0.0.10"))).To(BeFalse())
+			Expect(s.Contains(net.ParseIP("10.0.0.20"))).To(BeTrue())
+			Expect(s.Contains(net.ParseIP("10.0.0.21"))).To(BeFalse())
+			Expect(s.Contains(net.ParseIP("2001:db8::1"))).To(BeTrue())
+			Expect(s.Contains(net.ParseIP("2001:db9::1"))).To(BeFalse())
+			Expect(s.Contains(net.ParseIP("::a00:1"))).To(BeFalse())
+		})
+	})
+
+	Describe("Size and CIDRs", func() {
+		It("counts every address and covers the set with CIDR blocks", func() {
+			s := newIPSet("10.0.0.0-10.0.0.9", "::/64")
+
+			expected := new(big.Int).Lsh(big.NewInt(1), 64)
+			expected.Add(expected, big.NewInt(10))
+			Expect(s.Size()).To(Equal(expected))
+
+			var cidrs []string
+			for _, ipNet := range s.CIDRs() {
+				cidrs = append(cidrs, ipNet.String())
+			}
+			Expect(cidrs).To(Equal([]string{"10.0.0.0/29", "10.0.0.8/31", "::/64"}))
+		})
+	})
+})
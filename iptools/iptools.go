@@ -23,35 +23,78 @@ func PrivateIPRanges() []IPRange {
 }
 
 func PublicIPRanges() []IPRange {
-	nets := []net.IP{
+	return complementRanges(
 		net.IP{0, 0, 0, 0},
+		net.IP{255, 255, 255, 255},
+		PrivateIPRanges(),
+	)
+}
+
+// PrivateIPv6Ranges returns the Unique Local Address block, fc00::/7.
+func PrivateIPv6Ranges() []IPRange {
+	return []IPRange{
+		{
+			Start: net.ParseIP("fc00::"),
+			End:   net.ParseIP("fdff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+		},
+	}
+}
+
+// PublicIPv6Ranges returns the IPv6 address space outside the Unique Local
+// Address block and the IPv4-mapped block, ::ffff:0:0/96, whose addresses
+// stand for IPv4 addresses, private ones included.
+func PublicIPv6Ranges() []IPRange {
+	return complementRanges(
+		net.ParseIP("::"),
+		net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+		append([]IPRange{ipv4MappedRange()}, PrivateIPv6Ranges()...),
+	)
+}
+
+// ipv4MappedRange returns ::ffff:0:0/96. net.IP treats its addresses as IPv4
+// addresses, so it can only be used as a bound here and not in an IPSet, which
+// would take it for the whole IPv4 address space.
+func ipv4MappedRange() IPRange {
+	return IPRange{
+		Start: net.ParseIP("::ffff:0:0"),
+		End:   net.ParseIP("::ffff:ffff:ffff"),
 	}
+}
+
+// complementRanges returns the ranges between first and last that are not
+// covered by the given sorted, non-overlapping ranges.
+func complementRanges(first, last net.IP, ranges []IPRange) []IPRange {
+	nets := []net.IP{first}
 
-	for _, ipRange := range PrivateIPRanges() {
+	for _, ipRange := range ranges {
 		nets = append(nets, Dec(ipRange.Start), Inc(ipRange.End))
 	}
 
-	nets = append(nets, net.IP{255, 255, 255, 255})
+	nets = append(nets, last)
 
-	ranges := []IPRange{}
+	complement := []IPRange{}
 	for i := 0; i < len(nets); i += 2 {
-		ranges = append(ranges, IPRange{
+		complement = append(complement, IPRange{
 			Start: nets[i],
 			End:   nets[i+1],
 		})
 	}
 
-	return ranges
+	return complement
 }
 
 func NetworkRange(ipNet *net.IPNet) (net.IP, net.IP) {
 	// Inspired by https://github.com/docker/libnetwork/blob/master/netutils/utils.go
-	ip4 := ipNet.IP.To4()
-	min := ip4.Mask(ipNet.Mask)
+	ip := ipNet.IP.To4()
+	if ip == nil || len(ipNet.Mask) == net.IPv6len {
+		ip = ipNet.IP.To16()
+	}
+
+	min := ip.Mask(ipNet.Mask)
 	max := make([]byte, len(min))
 
 	for i := range min {
-		max[i] = ip4[i] | ^ipNet.Mask[i]
+		max[i] = ip[i] | ^ipNet.Mask[i]
 	}
 
 	return min, max
@@ -87,6 +130,14 @@ func NetworkOverlaps(left, right *net.IPNet) bool {
 	return left.Contains(right.IP) || right.Contains(left.IP)
 }
 
+func normalizeIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+
+	return ip.To16()
+}
+
 func CopyIP(from net.IP) net.IP {
 	to := make(net.IP, len(from))
 	copy(to, from)
@@ -102,12 +153,12 @@ func SliceNetFromRange(ipRange IPRange, ipNet *net.IPNet) []IPRange {
 		return nil
 	}
 
-	rangeStart := ipRange.Start.To4()
-	rangeEnd := ipRange.End.To4()
+	rangeStart := normalizeIP(ipRange.Start)
+	rangeEnd := normalizeIP(ipRange.End)
 
 	min, max := NetworkRange(ipNet)
-	netMin := min.To4()
-	netMax := max.To4()
+	netMin := normalizeIP(min)
+	netMax := normalizeIP(max)
 
 	var ipRanges []IPRange
 	if bytes.Compare(rangeStart, netMin) == -1 {
@@ -104,4 +104,34 @@ var _ = Describe("iptools", func() {
 			})
 		})
 	})
+
+	Describe("NetworkRange", func() {
+		It("returns the first and last IPs of an IPv4 network", func() {
+			_, ipNet, err := net.ParseCIDR("10.10.0.0/22")
+			Expect(err).NotTo(HaveOccurred())
+
+			min, max := iptools.NetworkRange(ipNet)
+			Expect(min).To(Equal(net.IP{10, 10, 0, 0}))
+			Expect(max).To(Equal(net.IP{10, 10, 3, 255}))
+		})
+
+		It("returns the first and last IPs of an IPv6 network", func() {
+			_, ipNet, err := net.ParseCIDR("2001:db8::/48")
+			Expect(err).NotTo(HaveOccurred())
+
+			min, max := iptools.NetworkRange(ipNet)
+			Expect(min.Equal(net.ParseIP("2001:db8::"))).To(BeTrue())
+			Expect(max.Equal(net.ParseIP("2001:db8:0:ffff:ffff:ffff:ffff:ffff"))).To(BeTrue())
+		})
+	})
+
+	Describe("PublicIPv6Ranges", func() {
+		It("returns everything outside of ::ffff:0:0/96 and fc00::/7", func() {
+			ranges := iptools.PublicIPv6Ranges()
+			Expect(ranges).To(HaveLen(3))
+			Expect(ranges[0].String()).To(Equal("::-::fffe:ffff:ffff"))
+			Expect(ranges[1].String()).To(Equal("::1:0:0:0-fbff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"))
+			Expect(ranges[2].String()).To(Equal("fe00::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"))
+		})
+	})
 })
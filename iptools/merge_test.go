@@ -0,0 +1,58 @@
+package iptools_test
+
+import (
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Merge", func() {
+	parseRanges := func(strs ...string) []iptools.IPRange {
+		var ipRanges []iptools.IPRange
+		for _, str := range strs {
+			ipRange, err := iptools.ParseIPRange(str)
+			Expect(err).NotTo(HaveOccurred())
+			ipRanges = append(ipRanges, ipRange)
+		}
+		return ipRanges
+	}
+
+	mergedStrings := func(strs ...string) []string {
+		var merged []string
+		for _, ipRange := range iptools.Merge(parseRanges(strs...)) {
+			merged = append(merged, ipRange.String())
+		}
+		return merged
+	}
+
+	It("returns nil for no ranges", func() {
+		Expect(iptools.Merge(nil)).To(BeNil())
+	})
+
+	It("merges adjacent ranges", func() {
+		Expect(mergedStrings("10.0.0.128/25", "10.0.0.0/25")).To(Equal([]string{"10.0.0.0-10.0.0.255"}))
+	})
+
+	It("merges overlapping and contained ranges", func() {
+		Expect(mergedStrings("10.0.0.0-10.0.0.10", "10.0.0.5-10.0.0.20", "10.0.0.7", "10.0.0.21")).To(Equal([]string{"10.0.0.0-10.0.0.21"}))
+	})
+
+	It("sorts disjoint ranges and keeps them separate", func() {
+		Expect(mergedStrings("10.0.0.9", "10.0.0.5", "10.0.0.7")).To(Equal([]string{"10.0.0.5", "10.0.0.7", "10.0.0.9"}))
+	})
+
+	It("keeps address families separate, IPv4 first", func() {
+		Expect(mergedStrings("::/96", "0.0.0.0-0.0.0.255", "::1:0:0/96")).To(Equal([]string{
+			"0.0.0.0-0.0.0.255",
+			"::-::1:ffff:ffff",
+		}))
+	})
+
+	It("merges ranges ending at the last address", func() {
+		Expect(mergedStrings("255.255.255.0/24", "255.255.255.255", "0.0.0.0")).To(Equal([]string{
+			"0.0.0.0",
+			"255.255.255.0-255.255.255.255",
+		}))
+	})
+})
@@ -0,0 +1,81 @@
+package iptools
+
+import (
+	"fmt"
+	"net"
+)
+
+const (
+	SpecialPurposeAll       = "all"
+	SpecialPurposeLinkLocal = "link-local"
+)
+
+// specialPurposeClasses groups entries of the IANA IPv4 and IPv6
+// Special-Purpose Address Registries (plus multicast) that are not reachable
+// or not meant to be reached as ordinary public destinations. Classes that are
+// never routed to a unicast destination on the internet are non-routable; the
+// IETF protocol assignments and the 6to4 relay anycast block contain
+// globally reachable addresses and are not.
+var specialPurposeClasses = []struct {
+	name        string
+	cidrs       []string
+	nonRoutable bool
+}{
+	{"this-network", []string{"0.0.0.0/8", "::/128"}, true},
+	{"loopback", []string{"127.0.0.0/8", "::1/128"}, true},
+	{"shared-address-space", []string{"100.64.0.0/10"}, true},
+	{"link-local", []string{"169.254.0.0/16", "fe80::/10"}, true},
+	{"ietf-protocol-assignments", []string{"192.0.0.0/24", "2001::/23"}, false},
+	{"documentation", []string{"192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24", "2001:db8::/32"}, true},
+	{"6to4-relay-anycast", []string{"192.88.99.0/24"}, false},
+	{"benchmarking", []string{"198.18.0.0/15", "2001:2::/48"}, true},
+	{"discard-only", []string{"100::/64"}, true},
+	{"multicast", []string{"224.0.0.0/4", "ff00::/8"}, true},
+	{"reserved", []string{"240.0.0.0/4"}, true},
+}
+
+func SpecialPurposeClasses() []string {
+	var names []string
+	for _, class := range specialPurposeClasses {
+		names = append(names, class.name)
+	}
+
+	return names
+}
+
+func NonRoutableSpecialPurposeClasses() []string {
+	var names []string
+	for _, class := range specialPurposeClasses {
+		if class.nonRoutable {
+			names = append(names, class.name)
+		}
+	}
+
+	return names
+}
+
+// SpecialPurposeRanges returns the ranges belonging to the named class, or to
+// every class if the name is SpecialPurposeAll.
+func SpecialPurposeRanges(name string) ([]IPRange, error) {
+	var ranges []IPRange
+
+	for _, class := range specialPurposeClasses {
+		if name != SpecialPurposeAll && name != class.name {
+			continue
+		}
+
+		for _, cidr := range class.cidrs {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, NewIPRangeFromIPNet(ipNet))
+		}
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("unknown-special-purpose-class: %s", name)
+	}
+
+	return ranges, nil
+}
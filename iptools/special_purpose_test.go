@@ -0,0 +1,55 @@
+package iptools_test
+
+import (
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SpecialPurposeRanges", func() {
+	rangeStrings := func(ipRanges []iptools.IPRange) []string {
+		var strs []string
+		for i := range ipRanges {
+			strs = append(strs, ipRanges[i].String())
+		}
+		return strs
+	}
+
+	It("returns the IPv4 and IPv6 ranges of a class", func() {
+		ranges, err := iptools.SpecialPurposeRanges("loopback")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rangeStrings(ranges)).To(Equal([]string{
+			"127.0.0.0-127.255.255.255",
+			"::1",
+		}))
+	})
+
+	It("returns the ranges of every class for 'all'", func() {
+		ranges, err := iptools.SpecialPurposeRanges("all")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rangeStrings(ranges)).To(ContainElement("100.64.0.0-100.127.255.255"))
+		Expect(rangeStrings(ranges)).To(ContainElement("224.0.0.0-239.255.255.255"))
+		Expect(rangeStrings(ranges)).To(ContainElement("240.0.0.0-255.255.255.255"))
+	})
+
+	It("returns an error for an unknown class", func() {
+		_, err := iptools.SpecialPurposeRanges("bogus")
+		Expect(err).To(MatchError("unknown-special-purpose-class: bogus"))
+	})
+
+	It("lists every class", func() {
+		Expect(iptools.SpecialPurposeClasses()).To(ContainElement("multicast"))
+	})
+
+	It("lists the non-routable classes", func() {
+		classes := iptools.NonRoutableSpecialPurposeClasses()
+		Expect(classes).To(ContainElement("this-network"))
+		Expect(classes).To(ContainElement("loopback"))
+		Expect(classes).To(ContainElement("link-local"))
+		Expect(classes).To(ContainElement("multicast"))
+		Expect(classes).To(ContainElement("reserved"))
+		Expect(classes).NotTo(ContainElement("ietf-protocol-assignments"))
+		Expect(classes).NotTo(ContainElement("6to4-relay-anycast"))
+	})
+})
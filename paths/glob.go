@@ -0,0 +1,20 @@
+package paths
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Glob expands the pattern, returning an error if nothing matches.
+func Glob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand path '%s': %s", pattern, err)
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("path '%s' does not exist", pattern)
+	}
+
+	return matches, nil
+}
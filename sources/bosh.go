@@ -0,0 +1,334 @@
+package sources
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+	"github.com/cloudfoundry-incubator/candiedyaml"
+)
+
+// BOSHFilter selects the deployments, instance groups and cloud config
+// networks to collect ranges from. Empty deployments or instance groups
+// select all of them; networks must be named to be collected.
+type BOSHFilter struct {
+	Deployments    []string
+	InstanceGroups []string
+	Networks       []string
+}
+
+func (f BOSHFilter) deployment(name string) bool {
+	return len(f.Deployments) == 0 || contains(f.Deployments, name)
+}
+
+func (f BOSHFilter) instanceGroup(name string) bool {
+	return len(f.InstanceGroups) == 0 || contains(f.InstanceGroups, name)
+}
+
+type BOSHDirector struct {
+	URL               string
+	Client            string
+	ClientSecret      string
+	SkipSSLValidation bool
+
+	httpClient    *http.Client
+	authenticated bool
+	authorization string
+}
+
+type boshVM struct {
+	Job string   `json:"job"`
+	IPs []string `json:"ips"`
+}
+
+type boshDeployment struct {
+	Name string `json:"name"`
+}
+
+type boshConfig struct {
+	Content string `json:"content"`
+}
+
+// VMIPs returns the IPs of the VMs in the selected deployments and instance
+// groups, as reported by the director.
+func (d *BOSHDirector) VMIPs(filter BOSHFilter) ([]iptools.IPRange, error) {
+	deployments := filter.Deployments
+	if len(deployments) == 0 {
+		var all []boshDeployment
+		if err := d.get("/deployments", &all); err != nil {
+			return nil, err
+		}
+
+		for _, deployment := range all {
+			deployments = append(deployments, deployment.Name)
+		}
+	}
+
+	var ipRanges []iptools.IPRange
+	for _, deployment := range deployments {
+		var vms []boshVM
+		if err := d.get("/deployments/"+url.PathEscape(deployment)+"/vms", &vms); err != nil {
+			return nil, err
+		}
+
+		for _, vm := range vms {
+			if !filter.instanceGroup(vm.Job) {
+				continue
+			}
+
+			for _, ip := range vm.IPs {
+				ipRange, err := parseIP(ip)
+				if err != nil {
+					return nil, fmt.Errorf("deployment '%s': %s", deployment, err)
+				}
+				ipRanges = append(ipRanges, ipRange)
+			}
+		}
+	}
+
+	return ipRanges, nil
+}
+
+// NetworkRanges returns the subnet ranges of the selected networks in the
+// director's latest cloud config.
+func (d *BOSHDirector) NetworkRanges(filter BOSHFilter) ([]iptools.IPRange, error) {
+	if len(filter.Networks) == 0 {
+		return nil, nil
+	}
+
+	var configs []boshConfig
+	if err := d.get("/configs?type=cloud&latest=true", &configs); err != nil {
+		return nil, err
+	}
+
+	var ipRanges []iptools.IPRange
+	for _, config := range configs {
+		configRanges, err := ParseCloudConfig([]byte(config.Content), filter)
+		if err != nil {
+			return nil, err
+		}
+		ipRanges = append(ipRanges, configRanges...)
+	}
+
+	return ipRanges, nil
+}
+
+func (d *BOSHDirector) get(path string, result interface{}) error {
+	if err := d.authenticate(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("GET", d.url()+path, nil)
+	if err != nil {
+		return err
+	}
+
+	if d.authorization != "" {
+		req.Header.Set("Authorization", d.authorization)
+	}
+
+	return d.do(req, result)
+}
+
+// authenticate determines how to authenticate from the director's info,
+// fetching a client credentials token from UAA if the director uses it.
+func (d *BOSHDirector) authenticate() error {
+	if d.authenticated {
+		return nil
+	}
+
+	d.httpClient = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: d.SkipSSLValidation},
+		},
+	}
+
+	req, err := http.NewRequest("GET", d.url()+"/info", nil)
+	if err != nil {
+		return err
+	}
+
+	var info struct {
+		UserAuthentication struct {
+			Type    string `json:"type"`
+			Options struct {
+				URL string `json:"url"`
+			} `json:"options"`
+		} `json:"user_authentication"`
+	}
+	if err := d.do(req, &info); err != nil {
+		return err
+	}
+
+	switch info.UserAuthentication.Type {
+	case "uaa":
+		form := url.Values{"grant_type": {"client_credentials"}}
+		req, err := http.NewRequest("POST", strings.TrimRight(info.UserAuthentication.Options.URL, "/")+"/oauth/token", strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(d.Client, d.ClientSecret)
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+			TokenType   string `json:"token_type"`
+		}
+		if err := d.do(req, &token); err != nil {
+			return err
+		}
+
+		d.authorization = "bearer " + token.AccessToken
+	case "basic":
+		d.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(d.Client+":"+d.ClientSecret))
+	}
+
+	d.authenticated = true
+
+	return nil
+}
+
+func (d *BOSHDirector) do(req *http.Request, result interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %s", req.Method, req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s failed with status %d: %s", req.Method, req.URL, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	err = json.Unmarshal(body, result)
+	if err != nil {
+		return fmt.Errorf("failed to parse response from %s %s: %s", req.Method, req.URL, err)
+	}
+
+	return nil
+}
+
+func (d *BOSHDirector) url() string {
+	directorURL := strings.TrimRight(d.URL, "/")
+	if !strings.Contains(directorURL, "://") {
+		directorURL = "https://" + directorURL
+	}
+
+	return directorURL
+}
+
+var deploymentTitlePattern = regexp.MustCompile(`^Deployment '(.*)'$`)
+
+// ParseVMsJSON returns the IPs of the VMs in the selected deployments and
+// instance groups from the output of `bosh vms --json`.
+func ParseVMsJSON(bs []byte, filter BOSHFilter) ([]iptools.IPRange, error) {
+	var output struct {
+		Tables []struct {
+			Title string `json:"Title"`
+			Rows  []struct {
+				Instance string `json:"instance"`
+				IPs      string `json:"ips"`
+			} `json:"Rows"`
+		} `json:"Tables"`
+	}
+
+	if err := json.Unmarshal(bs, &output); err != nil {
+		return nil, fmt.Errorf("failed to parse bosh vms output: %s", err)
+	}
+
+	var ipRanges []iptools.IPRange
+	for _, table := range output.Tables {
+		if match := deploymentTitlePattern.FindStringSubmatch(table.Title); match != nil && !filter.deployment(match[1]) {
+			continue
+		}
+
+		for _, row := range table.Rows {
+			instanceGroup := strings.SplitN(row.Instance, "/", 2)[0]
+			if !filter.instanceGroup(instanceGroup) {
+				continue
+			}
+
+			for _, ip := range strings.Fields(strings.Replace(row.IPs, ",", " ", -1)) {
+				ipRange, err := parseIP(ip)
+				if err != nil {
+					return nil, fmt.Errorf("instance '%s': %s", row.Instance, err)
+				}
+				ipRanges = append(ipRanges, ipRange)
+			}
+		}
+	}
+
+	return ipRanges, nil
+}
+
+// ParseCloudConfig returns the subnet ranges of the selected networks in a
+// BOSH cloud config.
+func ParseCloudConfig(bs []byte, filter BOSHFilter) ([]iptools.IPRange, error) {
+	var cloudConfig struct {
+		Networks []struct {
+			Name    string `yaml:"name"`
+			Subnets []struct {
+				Range string `yaml:"range"`
+			} `yaml:"subnets"`
+		} `yaml:"networks"`
+	}
+
+	if err := candiedyaml.Unmarshal(bs, &cloudConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse cloud config: %s", err)
+	}
+
+	var ipRanges []iptools.IPRange
+	for _, network := range cloudConfig.Networks {
+		if !contains(filter.Networks, network.Name) {
+			continue
+		}
+
+		for _, subnet := range network.Subnets {
+			if subnet.Range == "" {
+				continue
+			}
+
+			ipRange, err := parseRange(subnet.Range)
+			if err != nil {
+				return nil, fmt.Errorf("network '%s': %s", network.Name, err)
+			}
+			ipRanges = append(ipRanges, ipRange)
+		}
+	}
+
+	return ipRanges, nil
+}
+
+func parseIP(data string) (iptools.IPRange, error) {
+	ip := net.ParseIP(data)
+	if ip == nil {
+		return iptools.IPRange{}, fmt.Errorf("failed-to-parse-ip: %s", data)
+	}
+
+	return iptools.IPRange{Start: ip}, nil
+}
+
+func contains(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,146 @@
+package sources_test
+
+import (
+	"net/http"
+
+	"github.com/cloudfoundry-incubator/asg-creator/sources"
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BOSHDirector", func() {
+	var (
+		director *ghttp.Server
+		uaa      *ghttp.Server
+		client   *sources.BOSHDirector
+	)
+
+	BeforeEach(func() {
+		director = ghttp.NewServer()
+		uaa = ghttp.NewServer()
+
+		client = &sources.BOSHDirector{
+			URL:          director.URL(),
+			Client:       "admin",
+			ClientSecret: "secret",
+		}
+
+		director.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("GET", "/info"),
+			ghttp.RespondWith(http.StatusOK, `{"user_authentication": {"type": "uaa", "options": {"url": "`+uaa.URL()+`"}}}`),
+		))
+		uaa.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("POST", "/oauth/token"),
+			ghttp.VerifyBasicAuth("admin", "secret"),
+			ghttp.VerifyFormKV("grant_type", "client_credentials"),
+			ghttp.RespondWith(http.StatusOK, `{"access_token": "some-token", "token_type": "bearer"}`),
+		))
+	})
+
+	AfterEach(func() {
+		director.Close()
+		uaa.Close()
+	})
+
+	Describe("VMIPs", func() {
+		It("returns the IPs of the VMs in the selected instance groups", func() {
+			director.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/deployments/cf/vms"),
+				ghttp.VerifyHeaderKV("Authorization", "bearer some-token"),
+				ghttp.RespondWith(http.StatusOK, `[
+					{"job": "router", "index": 0, "ips": ["10.0.16.4"]},
+					{"job": "router", "index": 1, "ips": ["10.0.16.5", "10.0.32.5"]},
+					{"job": "diego-cell", "index": 0, "ips": ["10.0.16.20"]}
+				]`),
+			))
+
+			ipRanges, err := client.VMIPs(sources.BOSHFilter{
+				Deployments:    []string{"cf"},
+				InstanceGroups: []string{"router"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rangeStrings(ipRanges)).To(Equal([]string{"10.0.16.4", "10.0.16.5", "10.0.32.5"}))
+		})
+
+		It("queries every deployment when none are selected", func() {
+			director.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/deployments"),
+					ghttp.RespondWith(http.StatusOK, `[{"name": "cf"}, {"name": "redis"}]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/deployments/cf/vms"),
+					ghttp.RespondWith(http.StatusOK, `[{"job": "router", "ips": ["10.0.16.4"]}]`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/deployments/redis/vms"),
+					ghttp.RespondWith(http.StatusOK, `[{"job": "redis", "ips": ["10.0.48.4"]}]`),
+				),
+			)
+
+			ipRanges, err := client.VMIPs(sources.BOSHFilter{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rangeStrings(ipRanges)).To(Equal([]string{"10.0.16.4", "10.0.48.4"}))
+		})
+
+		It("returns an error when the director rejects the request", func() {
+			director.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, `{"code": 70000, "description": "Deployment 'bogus' doesn't exist"}`))
+
+			_, err := client.VMIPs(sources.BOSHFilter{Deployments: []string{"bogus"}})
+			Expect(err).To(MatchError(ContainSubstring("status 404")))
+			Expect(err).To(MatchError(ContainSubstring("Deployment 'bogus' doesn't exist")))
+		})
+	})
+
+	Describe("NetworkRanges", func() {
+		It("returns the subnet ranges of the selected networks in the latest cloud config", func() {
+			director.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/configs", "type=cloud&latest=true"),
+				ghttp.RespondWith(http.StatusOK, `[{"content": "networks:\n- name: default\n  subnets:\n  - range: 10.0.16.0/20\n- name: services\n  subnets:\n  - range: 10.0.48.0/20\n"}]`),
+			))
+
+			ipRanges, err := client.NetworkRanges(sources.BOSHFilter{Networks: []string{"services"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rangeStrings(ipRanges)).To(Equal([]string{"10.0.48.0-10.0.63.255"}))
+		})
+	})
+})
+
+var _ = Describe("ParseVMsJSON", func() {
+	vmsJSON := []byte(`{
+		"Tables": [
+			{
+				"Content": "vms",
+				"Title": "Deployment 'cf'",
+				"Rows": [
+					{"instance": "router/4b1c", "ips": "10.0.16.4", "process_state": "running"},
+					{"instance": "diego-cell/90ab", "ips": "10.0.16.20\n10.0.32.20", "process_state": "running"}
+				]
+			},
+			{
+				"Content": "vms",
+				"Title": "Deployment 'redis'",
+				"Rows": [
+					{"instance": "redis/77aa", "ips": "10.0.48.4", "process_state": "running"}
+				]
+			}
+		]
+	}`)
+
+	It("returns the IPs of every VM", func() {
+		ipRanges, err := sources.ParseVMsJSON(vmsJSON, sources.BOSHFilter{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rangeStrings(ipRanges)).To(Equal([]string{"10.0.16.4", "10.0.16.20", "10.0.32.20", "10.0.48.4"}))
+	})
+
+	It("filters by deployment and instance group", func() {
+		ipRanges, err := sources.ParseVMsJSON(vmsJSON, sources.BOSHFilter{
+			Deployments:    []string{"cf"},
+			InstanceGroups: []string{"diego-cell"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rangeStrings(ipRanges)).To(Equal([]string{"10.0.16.20", "10.0.32.20"}))
+	})
+})
@@ -0,0 +1,179 @@
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+)
+
+const (
+	ProviderAWS   = "aws"
+	ProviderGCP   = "gcp"
+	ProviderAzure = "azure"
+)
+
+var Providers = []string{ProviderAWS, ProviderGCP, ProviderAzure}
+
+// CloudFilter selects the prefixes of a cloud provider feed by service and
+// region, ignoring case. Empty services or regions select all of them.
+type CloudFilter struct {
+	Services []string
+	Regions  []string
+}
+
+func (f CloudFilter) matches(services []string, region string) bool {
+	if len(f.Regions) != 0 && !containsFold(f.Regions, region) {
+		return false
+	}
+
+	if len(f.Services) == 0 {
+		return true
+	}
+
+	for _, service := range services {
+		if containsFold(f.Services, service) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseCloudRanges returns the selected prefixes from a cloud provider IP
+// range feed: the AWS ip-ranges.json, the GCP cloud.json, or an Azure service
+// tags file.
+func ParseCloudRanges(provider string, bs []byte, filter CloudFilter) ([]iptools.IPRange, error) {
+	var (
+		prefixes []string
+		err      error
+	)
+
+	switch provider {
+	case ProviderAWS:
+		prefixes, err = awsPrefixes(bs, filter)
+	case ProviderGCP:
+		prefixes, err = gcpPrefixes(bs, filter)
+	case ProviderAzure:
+		prefixes, err = azurePrefixes(bs, filter)
+	default:
+		return nil, fmt.Errorf("unknown provider '%s'", provider)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s ranges: %s", provider, err)
+	}
+
+	var ipRanges []iptools.IPRange
+	for _, prefix := range prefixes {
+		ipRange, err := parseRange(prefix)
+		if err != nil {
+			return nil, err
+		}
+		ipRanges = append(ipRanges, ipRange)
+	}
+
+	return ipRanges, nil
+}
+
+func awsPrefixes(bs []byte, filter CloudFilter) ([]string, error) {
+	var feed struct {
+		Prefixes []struct {
+			IPPrefix string `json:"ip_prefix"`
+			Region   string `json:"region"`
+			Service  string `json:"service"`
+		} `json:"prefixes"`
+		IPv6Prefixes []struct {
+			IPv6Prefix string `json:"ipv6_prefix"`
+			Region     string `json:"region"`
+			Service    string `json:"service"`
+		} `json:"ipv6_prefixes"`
+	}
+
+	if err := json.Unmarshal(bs, &feed); err != nil {
+		return nil, err
+	}
+
+	var prefixes []string
+	for _, p := range feed.Prefixes {
+		if filter.matches([]string{p.Service}, p.Region) {
+			prefixes = append(prefixes, p.IPPrefix)
+		}
+	}
+	for _, p := range feed.IPv6Prefixes {
+		if filter.matches([]string{p.Service}, p.Region) {
+			prefixes = append(prefixes, p.IPv6Prefix)
+		}
+	}
+
+	return prefixes, nil
+}
+
+func gcpPrefixes(bs []byte, filter CloudFilter) ([]string, error) {
+	var feed struct {
+		Prefixes []struct {
+			IPv4Prefix string `json:"ipv4Prefix"`
+			IPv6Prefix string `json:"ipv6Prefix"`
+			Service    string `json:"service"`
+			Scope      string `json:"scope"`
+		} `json:"prefixes"`
+	}
+
+	if err := json.Unmarshal(bs, &feed); err != nil {
+		return nil, err
+	}
+
+	var prefixes []string
+	for _, p := range feed.Prefixes {
+		if !filter.matches([]string{p.Service}, p.Scope) {
+			continue
+		}
+
+		if p.IPv4Prefix != "" {
+			prefixes = append(prefixes, p.IPv4Prefix)
+		}
+		if p.IPv6Prefix != "" {
+			prefixes = append(prefixes, p.IPv6Prefix)
+		}
+	}
+
+	return prefixes, nil
+}
+
+// azurePrefixes matches services against both the service tag name, e.g.
+// Storage.WestEurope, and its system service, e.g. AzureStorage.
+func azurePrefixes(bs []byte, filter CloudFilter) ([]string, error) {
+	var feed struct {
+		Values []struct {
+			Name       string `json:"name"`
+			Properties struct {
+				Region          string   `json:"region"`
+				SystemService   string   `json:"systemService"`
+				AddressPrefixes []string `json:"addressPrefixes"`
+			} `json:"properties"`
+		} `json:"values"`
+	}
+
+	if err := json.Unmarshal(bs, &feed); err != nil {
+		return nil, err
+	}
+
+	var prefixes []string
+	for _, v := range feed.Values {
+		if filter.matches([]string{v.Name, v.Properties.SystemService}, v.Properties.Region) {
+			prefixes = append(prefixes, v.Properties.AddressPrefixes...)
+		}
+	}
+
+	return prefixes, nil
+}
+
+func containsFold(strs []string, str string) bool {
+	for _, s := range strs {
+		if strings.EqualFold(s, str) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,96 @@
+package sources_test
+
+import (
+	"github.com/cloudfoundry-incubator/asg-creator/sources"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseCloudRanges", func() {
+	Context("with the AWS ip-ranges.json format", func() {
+		feed := []byte(`{
+			"syncToken": "1700000000",
+			"prefixes": [
+				{"ip_prefix": "3.5.140.0/22", "region": "ap-northeast-2", "service": "S3", "network_border_group": "ap-northeast-2"},
+				{"ip_prefix": "52.216.0.0/15", "region": "us-east-1", "service": "S3", "network_border_group": "us-east-1"},
+				{"ip_prefix": "52.94.0.0/22", "region": "us-east-1", "service": "AMAZON", "network_border_group": "us-east-1"}
+			],
+			"ipv6_prefixes": [
+				{"ipv6_prefix": "2600:1fa0:8000::/39", "region": "us-east-1", "service": "S3", "network_border_group": "us-east-1"}
+			]
+		}`)
+
+		It("returns the IPv4 and IPv6 prefixes of the selected services and regions", func() {
+			ipRanges, err := sources.ParseCloudRanges("aws", feed, sources.CloudFilter{
+				Services: []string{"s3"},
+				Regions:  []string{"us-east-1"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rangeStrings(ipRanges)).To(Equal([]string{
+				"52.216.0.0-52.217.255.255",
+				"2600:1fa0:8000::-2600:1fa0:81ff:ffff:ffff:ffff:ffff:ffff",
+			}))
+		})
+	})
+
+	Context("with the GCP cloud.json format", func() {
+		feed := []byte(`{
+			"syncToken": "1700000000",
+			"prefixes": [
+				{"ipv4Prefix": "34.80.0.0/15", "service": "Google Cloud", "scope": "asia-east1"},
+				{"ipv6Prefix": "2600:1900:4180::/44", "service": "Google Cloud", "scope": "us-west4"},
+				{"ipv4Prefix": "34.16.0.0/17", "service": "Google Cloud", "scope": "us-west4"}
+			]
+		}`)
+
+		It("returns the prefixes of the selected scopes", func() {
+			ipRanges, err := sources.ParseCloudRanges("gcp", feed, sources.CloudFilter{Regions: []string{"us-west4"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rangeStrings(ipRanges)).To(Equal([]string{
+				"2600:1900:4180::-2600:1900:418f:ffff:ffff:ffff:ffff:ffff",
+				"34.16.0.0-34.16.127.255",
+			}))
+		})
+	})
+
+	Context("with the Azure service tags format", func() {
+		feed := []byte(`{
+			"changeNumber": 1,
+			"cloud": "Public",
+			"values": [
+				{"name": "Storage.WestEurope", "id": "Storage.WestEurope", "properties": {"region": "westeurope", "systemService": "AzureStorage", "addressPrefixes": ["20.38.108.0/23", "2603:1020:206::/48"]}},
+				{"name": "Sql.WestEurope", "id": "Sql.WestEurope", "properties": {"region": "westeurope", "systemService": "AzureSQL", "addressPrefixes": ["13.69.105.0/24"]}},
+				{"name": "Storage.EastUS", "id": "Storage.EastUS", "properties": {"region": "eastus", "systemService": "AzureStorage", "addressPrefixes": ["20.38.98.0/24"]}}
+			]
+		}`)
+
+		It("matches services by system service", func() {
+			ipRanges, err := sources.ParseCloudRanges("azure", feed, sources.CloudFilter{
+				Services: []string{"AzureStorage"},
+				Regions:  []string{"westeurope"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rangeStrings(ipRanges)).To(Equal([]string{
+				"20.38.108.0-20.38.109.255",
+				"2603:1020:206::-2603:1020:206:ffff:ffff:ffff:ffff:ffff",
+			}))
+		})
+
+		It("matches services by service tag name", func() {
+			ipRanges, err := sources.ParseCloudRanges("azure", feed, sources.CloudFilter{Services: []string{"Sql.WestEurope"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rangeStrings(ipRanges)).To(Equal([]string{"13.69.105.0-13.69.105.255"}))
+		})
+	})
+
+	It("returns an error for an unknown provider", func() {
+		_, err := sources.ParseCloudRanges("oracle", []byte(`{}`), sources.CloudFilter{})
+		Expect(err).To(MatchError("unknown provider 'oracle'"))
+	})
+
+	It("returns an error for an invalid feed", func() {
+		_, err := sources.ParseCloudRanges("aws", []byte(`{"prefixes": [{"ip_prefix": "bogus/22"}]}`), sources.CloudFilter{})
+		Expect(err).To(MatchError(ContainSubstring("bogus/22")))
+	})
+})
@@ -0,0 +1,121 @@
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+	"github.com/cloudfoundry-incubator/asg-creator/paths"
+)
+
+// Files expands the pattern and replaces each matching directory with the
+// files directly inside it, in lexical order. Hidden files are skipped.
+func Files(pattern string) ([]string, error) {
+	matches, err := paths.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, match)
+			continue
+		}
+
+		infos, err := ioutil.ReadDir(match)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, info := range infos {
+			if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+				continue
+			}
+			files = append(files, filepath.Join(match, info.Name()))
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// ParseRanges parses the contents of a range file, which is either a JSON
+// array of strings or one IP, CIDR or IP range per line. In the line format,
+// blank lines and anything after a # are ignored. Invalid entries are returned
+// as problems naming the file and the line or entry.
+func ParseRanges(name string, bs []byte) ([]iptools.IPRange, []error) {
+	if bytes.HasPrefix(bytes.TrimSpace(bs), []byte("[")) {
+		var entries []string
+		if err := json.Unmarshal(bs, &entries); err != nil {
+			return nil, []error{fmt.Errorf("%s: failed to parse JSON array: %s", name, err)}
+		}
+
+		var (
+			ipRanges []iptools.IPRange
+			problems []error
+		)
+		for i, entry := range entries {
+			ipRange, err := parseRange(entry)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("%s: entry %d: %s", name, i+1, err))
+				continue
+			}
+			ipRanges = append(ipRanges, ipRange)
+		}
+
+		return ipRanges, problems
+	}
+
+	var (
+		ipRanges []iptools.IPRange
+		problems []error
+		lineNum  int
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	for scanner.Scan() {
+		lineNum++
+
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		ipRange, err := parseRange(line)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: line %d: %s", name, lineNum, err))
+			continue
+		}
+		ipRanges = append(ipRanges, ipRange)
+	}
+
+	return ipRanges, problems
+}
+
+func parseRange(data string) (iptools.IPRange, error) {
+	ipRange, err := iptools.ParseIPRange(data)
+	if err != nil {
+		return iptools.IPRange{}, err
+	}
+
+	return ipRange, ipRange.Validate()
+}
@@ -0,0 +1,89 @@
+package sources_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry-incubator/asg-creator/sources"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Files", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(dir, "lists", "nested"), 0755)).To(Succeed())
+		for _, name := range []string{"a.txt", "b.json", "lists/d.txt", "lists/c.txt", "lists/.hidden", "lists/nested/e.txt"} {
+			Expect(ioutil.WriteFile(filepath.Join(dir, name), nil, 0644)).To(Succeed())
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("expands globs", func() {
+		files, err := sources.Files(filepath.Join(dir, "*.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(Equal([]string{filepath.Join(dir, "a.txt")}))
+	})
+
+	It("expands directories into the files directly inside them", func() {
+		files, err := sources.Files(filepath.Join(dir, "lists"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(Equal([]string{
+			filepath.Join(dir, "lists", "c.txt"),
+			filepath.Join(dir, "lists", "d.txt"),
+		}))
+	})
+
+	It("returns an error when nothing matches", func() {
+		_, err := sources.Files(filepath.Join(dir, "*.yml"))
+		Expect(err).To(MatchError("path '" + filepath.Join(dir, "*.yml") + "' does not exist"))
+	})
+})
+
+var _ = Describe("ParseRanges", func() {
+	It("parses one entry per line, ignoring comments and blank lines", func() {
+		ipRanges, problems := sources.ParseRanges("list.txt", []byte(`
+# service VMs
+10.0.0.1
+10.0.1.0/24   # redis
+
+2001:db8::1-2001:db8::ff
+`))
+		Expect(problems).To(BeEmpty())
+		Expect(rangeStrings(ipRanges)).To(Equal([]string{
+			"10.0.0.1",
+			"10.0.1.0-10.0.1.255",
+			"2001:db8::1-2001:db8::ff",
+		}))
+	})
+
+	It("parses a JSON array", func() {
+		ipRanges, problems := sources.ParseRanges("list.json", []byte(`["10.0.0.1", "10.0.1.0/24"]`))
+		Expect(problems).To(BeEmpty())
+		Expect(rangeStrings(ipRanges)).To(Equal([]string{"10.0.0.1", "10.0.1.0-10.0.1.255"}))
+	})
+
+	It("reports invalid lines", func() {
+		ipRanges, problems := sources.ParseRanges("list.txt", []byte("10.0.0.1\n10.0.0.9-10.0.0.1\nbogus\n"))
+		Expect(rangeStrings(ipRanges)).To(Equal([]string{"10.0.0.1"}))
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0]).To(MatchError("list.txt: line 2: invalid-range-start-after-end: 10.0.0.9-10.0.0.1"))
+		Expect(problems[1]).To(MatchError("list.txt: line 3: failed-to-parse-ip: bogus"))
+	})
+
+	It("reports invalid JSON entries", func() {
+		_, problems := sources.ParseRanges("list.json", []byte(`["10.0.0.1", "10.0.0.5-fd00::1"]`))
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0]).To(MatchError("list.json: entry 2: invalid-range-mixed-address-families: 10.0.0.5-fd00::1"))
+	})
+})
@@ -0,0 +1,23 @@
+package sources_test
+
+import (
+	"testing"
+
+	"github.com/cloudfoundry-incubator/asg-creator/iptools"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSources(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Sources Suite")
+}
+
+func rangeStrings(ipRanges []iptools.IPRange) []string {
+	var strs []string
+	for i := range ipRanges {
+		strs = append(strs, ipRanges[i].String())
+	}
+	return strs
+}